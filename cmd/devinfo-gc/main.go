@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/golang/glog"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/devinfogc"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+)
+
+var (
+	kuberconfig   = flag.String("kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
+	master        = flag.String("master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	nodeName      = flag.String("node-name", os.Getenv("NODE_NAME"), "Name of the node whose device information is collected. Defaults to $NODE_NAME.")
+	devInfoDir    = flag.String("devinfo-dir", utils.DefaultDeviceInfoPath, "Root directory of the device information files.")
+	liveDeviceIDs = flag.String("live-device-ids", "", "Comma-separated device IDs whose device information must be kept.")
+	resourceNames = flag.String("resource-names", "", "Comma-separated Device Plugin resource names whose device information is matched by filename.")
+	minAge        = flag.Duration("min-age", devinfogc.DefaultMinAge, "Only collect device information older than this.")
+	interval      = flag.Duration("interval", 0, "Collect periodically at this interval. Collects once and exits when zero.")
+	dryRun        = flag.Bool("dry-run", false, "Only print the device information that would be removed.")
+)
+
+func main() {
+	flag.Parse()
+
+	cfg, err := clientcmd.BuildConfigFromFlags(*master, *kuberconfig)
+	if err != nil {
+		glog.Fatalf("Error building kubeconfig: %v", err)
+	}
+
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		glog.Fatalf("Error building kubernetes clientset: %v", err)
+	}
+
+	var ids []string
+	if *liveDeviceIDs != "" {
+		ids = strings.Split(*liveDeviceIDs, ",")
+	}
+
+	var names []string
+	if *resourceNames != "" {
+		names = strings.Split(*resourceNames, ",")
+	}
+
+	collector, err := devinfogc.NewCollector(devinfogc.Options{
+		Store:    utils.NewFSDeviceInfoStore(*devInfoDir),
+		Client:   client,
+		NodeName: *nodeName,
+		LiveDeviceIDs: func(context.Context) ([]string, error) {
+			return ids, nil
+		},
+		ResourceNames: names,
+		MinAge:        *minAge,
+		DryRun:        *dryRun,
+		Out:           os.Stdout,
+	})
+	if err != nil {
+		glog.Fatalf("Error creating device information collector: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if *interval == 0 {
+		if _, err := collector.Collect(ctx); err != nil {
+			glog.Fatalf("Error collecting device information: %v", err)
+		}
+		return
+	}
+
+	glog.Infof("collecting device information below %s every %s", *devInfoDir, interval.String())
+	collector.Run(ctx, *interval)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	clientset "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/clientset/versioned"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/informers/externalversions"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/nadaudit"
+)
+
+var (
+	kuberconfig = flag.String("kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
+	master      = flag.String("master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <command> [command flags]\n\nCommands:\n", os.Args[0])
+	fmt.Fprintf(flag.CommandLine.Output(), "  audit\treport network attachment definitions with overlapping IPAM ranges\n\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch flag.Arg(0) {
+	case "audit":
+		os.Exit(audit(flag.Args()[1:]))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+}
+
+// audit runs the audit subcommand and returns the exit code: 1 if conflicts
+// or, with -strict, any finding was reported
+func audit(args []string) int {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	namespace := fs.String("namespace", "", "Only audit this namespace. Audits all namespaces when empty.")
+	strict := fs.Bool("strict", false, "Also fail on overlaps across links and invalid configurations.")
+	timeout := fs.Duration("timeout", time.Minute, "Time to wait for the network attachment definitions to be listed.")
+	fs.Parse(args)
+
+	cfg, err := clientcmd.BuildConfigFromFlags(*master, *kuberconfig)
+	if err != nil {
+		glog.Fatalf("Error building kubeconfig: %v", err)
+	}
+
+	client, err := clientset.NewForConfig(cfg)
+	if err != nil {
+		glog.Fatalf("Error building clientset: %v", err)
+	}
+
+	factory := externalversions.NewSharedInformerFactoryWithOptions(client, 0, externalversions.WithNamespace(*namespace))
+	informer := factory.K8sCniCncfIo().V1().NetworkAttachmentDefinitions()
+	lister := informer.Lister()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.Informer().HasSynced) {
+		glog.Fatalf("Timed out listing network attachment definitions")
+	}
+
+	findings, err := nadaudit.Audit(lister)
+	if err != nil {
+		glog.Fatalf("Error auditing network attachment definitions: %v", err)
+	}
+
+	code := 0
+	for _, finding := range findings {
+		fmt.Println(finding)
+		if finding.Kind == nadaudit.Conflict || *strict {
+			code = 1
+		}
+	}
+	return code
+}
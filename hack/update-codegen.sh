@@ -8,5 +8,5 @@ SCRIPT_ROOT=$(dirname ${BASH_SOURCE})/..
 
 bash vendor/k8s.io/code-generator/generate-groups.sh all \
   github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis \
-  k8s.cni.cncf.io:v1 \
+  k8s.cni.cncf.io:v1,v1beta1 \
   --go-header-file ${SCRIPT_ROOT}/hack/custom-boilerplate.go.txt
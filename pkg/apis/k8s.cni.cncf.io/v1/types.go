@@ -43,18 +43,27 @@ const (
 	DeviceInfoTypeVHostUser = "vhost-user"
 	DeviceInfoTypeMemif     = "memif"
 	DeviceInfoTypeVDPA      = "vdpa"
-	DeviceInfoVersion       = "1.0.0"
+	// Types introduced with DeviceInfoVersion110
+	DeviceInfoTypeAuxiliary = "auxiliary"
+	DeviceInfoTypeAFXDP     = "af-xdp"
+
+	DeviceInfoVersion100 = "1.0.0"
+	DeviceInfoVersion110 = "1.1.0"
+	// DeviceInfoVersion is the latest version of the DeviceInfo format
+	DeviceInfoVersion = DeviceInfoVersion110
 )
 
 // DeviceInfo contains the information of the device associated
 // with this network (if any)
 type DeviceInfo struct {
-	Type      string       `json:"type,omitempty"`
-	Version   string       `json:"version,omitempty"`
-	Pci       *PciDevice   `json:"pci,omitempty"`
-	Vdpa      *VdpaDevice  `json:"vdpa,omitempty"`
-	VhostUser *VhostDevice `json:"vhost-user,omitempty"`
-	Memif     *MemifDevice `json:"memif,omitempty"`
+	Type      string           `json:"type,omitempty"`
+	Version   string           `json:"version,omitempty"`
+	Pci       *PciDevice       `json:"pci,omitempty"`
+	Vdpa      *VdpaDevice      `json:"vdpa,omitempty"`
+	VhostUser *VhostDevice     `json:"vhost-user,omitempty"`
+	Memif     *MemifDevice     `json:"memif,omitempty"`
+	Auxiliary *AuxiliaryDevice `json:"auxiliary,omitempty"`
+	AFXDP     *AFXDPDevice     `json:"af-xdp,omitempty"`
 }
 
 type PciDevice struct {
@@ -96,6 +105,25 @@ type MemifDevice struct {
 	Mode string `json:"mode,omitempty"`
 }
 
+// AuxiliaryDevice describes a device on the auxiliary bus, such as a
+// Scalable Function carved out of a PCI physical function
+type AuxiliaryDevice struct {
+	// DeviceName is the auxiliary bus device name, e.g. mlx5_core.sf.4
+	DeviceName string `json:"device-name,omitempty"`
+	// ParentPciAddress is the PCI address of the parent function
+	ParentPciAddress string `json:"parent-pci-address,omitempty"`
+	// Representor is the netdev name of the device's representor, if any
+	Representor string `json:"representor,omitempty"`
+}
+
+// AFXDPDevice describes a netdev handed to the pod for AF_XDP sockets
+type AFXDPDevice struct {
+	// Netdev is the name of the network device the sockets bind to
+	Netdev string `json:"netdev,omitempty"`
+	// Path is the unix domain socket used to obtain the XSK map, if any
+	Path string `json:"path,omitempty"`
+}
+
 // NetworkStatus is for network status annotation for pod
 // +k8s:deepcopy-gen=false
 type NetworkStatus struct {
@@ -167,6 +195,10 @@ const (
 	NetworkAttachmentAnnot = "k8s.v1.cni.cncf.io/networks"
 	// Pod annotation for network status
 	NetworkStatusAnnot = "k8s.v1.cni.cncf.io/network-status"
+	// Pod annotation for network status written by older Multus versions
+	LegacyNetworkStatusAnnot = "k8s.v1.cni.cncf.io/networks-status"
+	// Pod annotation overriding the cluster default network
+	DefaultNetworkAnnot = "v1.multus-cni.io/default-network"
 )
 
 // NoK8sNetworkError indicates error, no network in kubernetes
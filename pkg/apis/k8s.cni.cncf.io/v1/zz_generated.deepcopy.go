@@ -25,6 +25,38 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AFXDPDevice) DeepCopyInto(out *AFXDPDevice) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AFXDPDevice.
+func (in *AFXDPDevice) DeepCopy() *AFXDPDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(AFXDPDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuxiliaryDevice) DeepCopyInto(out *AuxiliaryDevice) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuxiliaryDevice.
+func (in *AuxiliaryDevice) DeepCopy() *AuxiliaryDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(AuxiliaryDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeviceInfo) DeepCopyInto(out *DeviceInfo) {
 	*out = *in
@@ -48,6 +80,16 @@ func (in *DeviceInfo) DeepCopyInto(out *DeviceInfo) {
 		*out = new(MemifDevice)
 		**out = **in
 	}
+	if in.Auxiliary != nil {
+		in, out := &in.Auxiliary, &out.Auxiliary
+		*out = new(AuxiliaryDevice)
+		**out = **in
+	}
+	if in.AFXDP != nil {
+		in, out := &in.AFXDP, &out.AFXDP
+		*out = new(AFXDPDevice)
+		**out = **in
+	}
 	return
 }
 
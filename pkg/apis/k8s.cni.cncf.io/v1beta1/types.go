@@ -0,0 +1,106 @@
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// PolicyForAnnotation is the MultiNetworkPolicy annotation listing the
+	// comma-separated NetworkAttachmentDefinitions the policy applies to
+	PolicyForAnnotation = "k8s.v1.cni.cncf.io/policy-for"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +resourceName=multi-networkpolicies
+
+// MultiNetworkPolicy is a NetworkPolicy for the secondary networks of pods,
+// selected by the PolicyForAnnotation
+type MultiNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec MultiNetworkPolicySpec `json:"spec,omitempty"`
+}
+
+// MultiPolicyType is the direction of traffic a MultiNetworkPolicy applies to
+type MultiPolicyType string
+
+const (
+	// PolicyTypeIngress applies a policy to traffic entering the pods
+	PolicyTypeIngress MultiPolicyType = "Ingress"
+	// PolicyTypeEgress applies a policy to traffic leaving the pods
+	PolicyTypeEgress MultiPolicyType = "Egress"
+)
+
+// MultiNetworkPolicySpec mirrors networking/v1 NetworkPolicySpec
+type MultiNetworkPolicySpec struct {
+	// PodSelector selects the pods the policy applies to. An empty selector
+	// selects all pods of the namespace.
+	PodSelector metav1.LabelSelector `json:"podSelector"`
+	// Ingress rules allow incoming traffic. Traffic is allowed if it matches
+	// at least one rule.
+	Ingress []MultiNetworkPolicyIngressRule `json:"ingress,omitempty"`
+	// Egress rules allow outgoing traffic. Traffic is allowed if it matches
+	// at least one rule.
+	Egress []MultiNetworkPolicyEgressRule `json:"egress,omitempty"`
+	// PolicyTypes lists the directions the policy applies to. Defaults to
+	// Ingress, plus Egress if there are egress rules.
+	PolicyTypes []MultiPolicyType `json:"policyTypes,omitempty"`
+}
+
+// MultiNetworkPolicyIngressRule allows traffic from peers to ports. Empty
+// lists match everything.
+type MultiNetworkPolicyIngressRule struct {
+	Ports []MultiNetworkPolicyPort `json:"ports,omitempty"`
+	From  []MultiNetworkPolicyPeer `json:"from,omitempty"`
+}
+
+// MultiNetworkPolicyEgressRule allows traffic to peers on ports. Empty lists
+// match everything.
+type MultiNetworkPolicyEgressRule struct {
+	Ports []MultiNetworkPolicyPort `json:"ports,omitempty"`
+	To    []MultiNetworkPolicyPeer `json:"to,omitempty"`
+}
+
+// MultiNetworkPolicyPort is a port or range of ports
+type MultiNetworkPolicyPort struct {
+	// Protocol defaults to TCP
+	Protocol *corev1.Protocol `json:"protocol,omitempty"`
+	// Port is a numbered or named port. All ports match if it is not set.
+	Port *intstr.IntOrString `json:"port,omitempty"`
+	// EndPort makes the port a range from Port to EndPort. Port must be
+	// numbered if it is set.
+	EndPort *int32 `json:"endPort,omitempty"`
+}
+
+// IPBlock is a CIDR, optionally without some of its subnets
+type IPBlock struct {
+	CIDR   string   `json:"cidr"`
+	Except []string `json:"except,omitempty"`
+}
+
+// MultiNetworkPolicyPeer selects traffic peers. Either IPBlock, or one or
+// both of the selectors may be set.
+type MultiNetworkPolicyPeer struct {
+	// PodSelector selects pods in the namespaces of NamespaceSelector, or in
+	// the policy's namespace if that is not set
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// NamespaceSelector selects all pods of the matching namespaces, or
+	// those matching PodSelector if it is set
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	IPBlock           *IPBlock              `json:"ipBlock,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MultiNetworkPolicyList is a list of MultiNetworkPolicy objects
+type MultiNetworkPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []MultiNetworkPolicy `json:"items"`
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	v1beta1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1beta1"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
 )
@@ -56,6 +57,10 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	case v1.SchemeGroupVersion.WithResource("network-attachment-definitions"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.K8sCniCncfIo().V1().NetworkAttachmentDefinitions().Informer()}, nil
 
+		// Group=k8s.cni.cncf.io, Version=v1beta1
+	case v1beta1.SchemeGroupVersion.WithResource("multi-networkpolicies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.K8sCniCncfIo().V1beta1().MultiNetworkPolicies().Informer()}, nil
+
 	}
 
 	return nil, fmt.Errorf("no informer found for %v", resource)
@@ -0,0 +1,221 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cniharness executes NetworkAttachmentDefinition configurations
+// through libcni against stub plugins, so that the resulting network status
+// can be asserted in unit tests without root privileges or real namespaces.
+//
+// Stub plugins are the test binary itself: the harness links each plugin
+// type to the running executable, which must call RunStubPluginIfInvoked
+// from its TestMain.
+package cniharness
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/containernetworking/cni/libcni"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/version"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+)
+
+const (
+	// DefaultContainerID is used when no RuntimeConf is given
+	DefaultContainerID = "cniharness"
+	// DefaultNetNS is used when no RuntimeConf is given. Stub plugins never
+	// enter the namespace, so the path does not need to exist.
+	DefaultNetNS = "/var/run/netns/cniharness"
+	// DefaultIfName is used when no RuntimeConf is given
+	DefaultIfName = "net1"
+)
+
+// Harness runs CNI commands for NetworkAttachmentDefinitions against stub plugins
+type Harness struct {
+	// ConfDir is the CNI configuration directory used for
+	// NetworkAttachmentDefinitions with an empty Spec
+	ConfDir string
+	// BinDir contains the stub plugins
+	BinDir string
+	// CacheDir is the libcni result cache directory
+	CacheDir string
+
+	executable string
+	cniConfig  *libcni.CNIConfig
+}
+
+// New creates a Harness with its plugin, configuration and cache
+// directories below dir
+func New(dir string) (*Harness, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find test executable: %v", err)
+	}
+
+	h := &Harness{
+		ConfDir:    filepath.Join(dir, "net.d"),
+		BinDir:     filepath.Join(dir, "bin"),
+		CacheDir:   filepath.Join(dir, "cache"),
+		executable: executable,
+	}
+	for _, d := range []string{h.ConfDir, h.BinDir, h.CacheDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return nil, err
+		}
+	}
+	h.cniConfig = libcni.NewCNIConfigWithCacheDir([]string{h.BinDir}, h.CacheDir, nil)
+
+	return h, nil
+}
+
+// AddPlugin installs a stub plugin that is executed for the given CNI plugin type
+func (h *Harness) AddPlugin(pluginType string, plugin StubPlugin) error {
+	pluginPath := filepath.Join(h.BinDir, pluginType)
+
+	data, err := json.Marshal(&plugin)
+	if err != nil {
+		return fmt.Errorf("AddPlugin: failed to marshal stub plugin %s: %v", pluginType, err)
+	}
+	if err := ioutil.WriteFile(pluginPath+stubSpecSuffix, data, 0644); err != nil {
+		return fmt.Errorf("AddPlugin: %v", err)
+	}
+
+	if _, err := os.Lstat(pluginPath); os.IsNotExist(err) {
+		if err := os.Symlink(h.executable, pluginPath); err != nil {
+			return fmt.Errorf("AddPlugin: %v", err)
+		}
+	}
+	return nil
+}
+
+// Invocations returns the commands executed so far by the stub plugin of the given type
+func (h *Harness) Invocations(pluginType string) ([]Invocation, error) {
+	f, err := os.Open(filepath.Join(h.BinDir, pluginType) + stubLogSuffix)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var invocations []Invocation
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var inv Invocation
+		if err := json.Unmarshal(scanner.Bytes(), &inv); err != nil {
+			return nil, err
+		}
+		invocations = append(invocations, inv)
+	}
+	return invocations, scanner.Err()
+}
+
+// Add resolves the configuration of net and executes the ADD command
+func (h *Harness) Add(ctx context.Context, net *v1.NetworkAttachmentDefinition, rt *libcni.RuntimeConf) (cnitypes.Result, error) {
+	confList, err := h.confList(net)
+	if err != nil {
+		return nil, err
+	}
+	return h.cniConfig.AddNetworkList(ctx, confList, runtimeConf(rt))
+}
+
+// Check resolves the configuration of net and executes the CHECK command
+func (h *Harness) Check(ctx context.Context, net *v1.NetworkAttachmentDefinition, rt *libcni.RuntimeConf) error {
+	confList, err := h.confList(net)
+	if err != nil {
+		return err
+	}
+	return h.cniConfig.CheckNetworkList(ctx, confList, runtimeConf(rt))
+}
+
+// Del resolves the configuration of net and executes the DEL command
+func (h *Harness) Del(ctx context.Context, net *v1.NetworkAttachmentDefinition, rt *libcni.RuntimeConf) error {
+	confList, err := h.confList(net)
+	if err != nil {
+		return err
+	}
+	return h.cniConfig.DelNetworkList(ctx, confList, runtimeConf(rt))
+}
+
+// Attach executes ADD followed by CHECK (when the configuration supports it)
+// and returns the network status an implementation would report for the
+// attachment. The status is named <namespace>/<name> after net.
+func (h *Harness) Attach(ctx context.Context, net *v1.NetworkAttachmentDefinition, rt *libcni.RuntimeConf, defaultNetwork bool, dev *v1.DeviceInfo) (*v1.NetworkStatus, error) {
+	rt = runtimeConf(rt)
+
+	result, err := h.Add(ctx, net, rt)
+	if err != nil {
+		return nil, fmt.Errorf("Attach: ADD failed: %v", err)
+	}
+
+	confList, err := h.confList(net)
+	if err != nil {
+		return nil, err
+	}
+	if supportsCheck(confList) {
+		if err := h.cniConfig.CheckNetworkList(ctx, confList, rt); err != nil {
+			return nil, fmt.Errorf("Attach: CHECK failed: %v", err)
+		}
+	}
+
+	return utils.CreateNetworkStatus(result, fmt.Sprintf("%s/%s", net.Namespace, net.Name), defaultNetwork, dev)
+}
+
+// confList resolves the CNI configuration of net the way a meta-plugin does
+func (h *Harness) confList(net *v1.NetworkAttachmentDefinition) (*libcni.NetworkConfigList, error) {
+	config, err := utils.GetCNIConfig(net, h.ConfDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var probe map[string]interface{}
+	if err := json.Unmarshal(config, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse CNI configuration of %s/%s: %v", net.Namespace, net.Name, err)
+	}
+	if _, ok := probe["plugins"]; ok {
+		return libcni.ConfListFromBytes(config)
+	}
+
+	conf, err := libcni.ConfFromBytes(config)
+	if err != nil {
+		return nil, err
+	}
+	return libcni.ConfListFromConf(conf)
+}
+
+// supportsCheck reports whether the configuration version knows the CHECK command
+func supportsCheck(confList *libcni.NetworkConfigList) bool {
+	gtet, err := version.GreaterThanOrEqualTo(confList.CNIVersion, "0.4.0")
+	return err == nil && gtet && !confList.DisableCheck
+}
+
+// runtimeConf returns rt, or a default RuntimeConf when it is nil
+func runtimeConf(rt *libcni.RuntimeConf) *libcni.RuntimeConf {
+	if rt != nil {
+		return rt
+	}
+	return &libcni.RuntimeConf{
+		ContainerID: DefaultContainerID,
+		NetNS:       DefaultNetNS,
+		IfName:      DefaultIfName,
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cniharness
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	cni100 "github.com/containernetworking/cni/pkg/types/100"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func ensureCIDR(cidr string) net.IPNet {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	Expect(err).NotTo(HaveOccurred())
+	ipNet.IP = ip
+	return *ipNet
+}
+
+var _ = Describe("CNI execution harness", func() {
+	var tmpDir string
+	var harness *Harness
+	var nad *v1.NetworkAttachmentDefinition
+
+	cannedResult := func() *cni100.Result {
+		return &cni100.Result{
+			CNIVersion: "1.0.0",
+			Interfaces: []*cni100.Interface{
+				{
+					Name:    "net1",
+					Mac:     "92:79:27:01:7c:cf",
+					Sandbox: DefaultNetNS,
+				},
+			},
+			IPs: []*cni100.IPConfig{
+				{
+					Interface: cni100.Int(0),
+					Address:   ensureCIDR("10.1.1.5/24"),
+				},
+			},
+			Routes: []*cnitypes.Route{
+				{
+					Dst: ensureCIDR("0.0.0.0/0"),
+					GW:  net.ParseIP("10.1.1.1"),
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "cniharness")
+		Expect(err).NotTo(HaveOccurred())
+		harness, err = New(tmpDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		nad = &v1.NetworkAttachmentDefinition{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-net",
+				Namespace: "testnamespace",
+			},
+			Spec: v1.NetworkAttachmentDefinitionSpec{
+				Config: `{
+					"cniVersion": "1.0.0",
+					"name": "test-net",
+					"plugins": [
+						{"type": "stub-main"},
+						{"type": "stub-meta"}
+					]
+				}`,
+			},
+		}
+		Expect(harness.AddPlugin("stub-main", StubPlugin{Result: cannedResult()})).To(Succeed())
+		Expect(harness.AddPlugin("stub-meta", StubPlugin{})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("creates the network status from the chained result", func() {
+		status, err := harness.Attach(context.TODO(), nad, nil, false, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Name).To(Equal("testnamespace/test-net"))
+		Expect(status.Interface).To(Equal("net1"))
+		Expect(status.Mac).To(Equal("92:79:27:01:7c:cf"))
+		Expect(status.IPs).To(Equal([]string{"10.1.1.5"}))
+		Expect(status.Gateway).To(Equal([]string{"10.1.1.1"}))
+	})
+
+	It("executes ADD, CHECK and DEL on every plugin of the chain", func() {
+		_, err := harness.Attach(context.TODO(), nad, nil, false, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(harness.Del(context.TODO(), nad, nil)).To(Succeed())
+
+		for _, plugin := range []string{"stub-main", "stub-meta"} {
+			invocations, err := harness.Invocations(plugin)
+			Expect(err).NotTo(HaveOccurred())
+			var commands []string
+			for _, inv := range invocations {
+				Expect(inv.ContainerID).To(Equal(DefaultContainerID))
+				Expect(inv.IfName).To(Equal(DefaultIfName))
+				commands = append(commands, inv.Command)
+			}
+			Expect(commands).To(Equal([]string{"ADD", "CHECK", "DEL"}))
+		}
+	})
+
+	It("passes the canned error of a failing plugin", func() {
+		Expect(harness.AddPlugin("stub-meta", StubPlugin{
+			Error:  &cnitypes.Error{Code: 100, Msg: "canned failure"},
+			FailOn: []string{"CHECK"},
+		})).To(Succeed())
+
+		_, err := harness.Add(context.TODO(), nad, nil)
+		Expect(err).NotTo(HaveOccurred())
+		err = harness.Check(context.TODO(), nad, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("canned failure"))
+	})
+
+	It("resolves a NetworkAttachmentDefinition with an empty Spec from ConfDir", func() {
+		conf := `{"cniVersion": "0.4.0", "name": "test-net", "type": "stub-main"}`
+		Expect(ioutil.WriteFile(filepath.Join(harness.ConfDir, "10-test.conf"), []byte(conf), 0644)).To(Succeed())
+		nad.Spec = v1.NetworkAttachmentDefinitionSpec{}
+
+		status, err := harness.Attach(context.TODO(), nad, nil, true, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Default).To(BeTrue())
+		Expect(status.IPs).To(Equal([]string{"10.1.1.5"}))
+	})
+})
@@ -0,0 +1,184 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cniharness
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	cni100 "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+const (
+	// stubSpecSuffix is appended to a stub plugin path to locate its canned behaviour
+	stubSpecSuffix = ".stub.json"
+	// stubLogSuffix is appended to a stub plugin path to locate its invocation log
+	stubLogSuffix = ".log"
+)
+
+// StubPlugin describes the canned behaviour of a fake CNI plugin
+type StubPlugin struct {
+	// Result is returned on ADD. When nil the plugin passes through the
+	// previous result of the chain, or an empty result if there is none.
+	Result *cni100.Result `json:"result,omitempty"`
+	// Error, when set, is returned by the commands listed in FailOn
+	Error *cnitypes.Error `json:"error,omitempty"`
+	// FailOn lists the CNI commands (ADD, CHECK, DEL) that return Error.
+	// When empty, Error is returned by every command.
+	FailOn []string `json:"failOn,omitempty"`
+}
+
+// Invocation records one execution of a stub plugin
+type Invocation struct {
+	Command     string `json:"command"`
+	ContainerID string `json:"containerID"`
+	IfName      string `json:"ifName"`
+	Netns       string `json:"netns,omitempty"`
+	Args        string `json:"args,omitempty"`
+	StdinData   string `json:"stdinData"`
+}
+
+// RunStubPluginIfInvoked turns the current process into a stub CNI plugin
+// when it was executed by a Harness, and exits once the command is handled.
+// Otherwise it returns immediately. It must be called from TestMain of every
+// test binary that uses a Harness, before the tests are run.
+func RunStubPluginIfInvoked() {
+	if os.Getenv("CNI_COMMAND") == "" {
+		return
+	}
+
+	plugin, err := loadStubPlugin(os.Args[0] + stubSpecSuffix)
+	if err != nil {
+		// Not started by a Harness
+		return
+	}
+
+	skel.PluginMain(
+		func(args *skel.CmdArgs) error { return plugin.cmdAdd(args) },
+		func(args *skel.CmdArgs) error { return plugin.cmdCheck(args) },
+		func(args *skel.CmdArgs) error { return plugin.cmdDel(args) },
+		version.All, "CNI stub plugin")
+	os.Exit(0)
+}
+
+// loadStubPlugin reads the canned behaviour of a stub plugin
+func loadStubPlugin(path string) (*StubPlugin, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	plugin := &StubPlugin{}
+	if err := json.Unmarshal(bytes, plugin); err != nil {
+		return nil, err
+	}
+	return plugin, nil
+}
+
+// fails reports whether the plugin is configured to fail the given command
+func (p *StubPlugin) fails(command string) bool {
+	if p.Error == nil {
+		return false
+	}
+	if len(p.FailOn) == 0 {
+		return true
+	}
+	for _, c := range p.FailOn {
+		if strings.EqualFold(c, command) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *StubPlugin) cmdAdd(args *skel.CmdArgs) error {
+	if err := recordInvocation("ADD", args); err != nil {
+		return err
+	}
+	if p.fails("ADD") {
+		return p.Error
+	}
+
+	conf := &cnitypes.NetConf{}
+	if err := json.Unmarshal(args.StdinData, conf); err != nil {
+		return fmt.Errorf("failed to parse network configuration: %v", err)
+	}
+
+	var result cnitypes.Result
+	if p.Result != nil {
+		result = p.Result
+	} else {
+		if err := version.ParsePrevResult(conf); err != nil {
+			return err
+		}
+		result = conf.PrevResult
+		if result == nil {
+			result = &cni100.Result{CNIVersion: cni100.ImplementedSpecVersion}
+		}
+	}
+
+	return cnitypes.PrintResult(result, conf.CNIVersion)
+}
+
+func (p *StubPlugin) cmdCheck(args *skel.CmdArgs) error {
+	if err := recordInvocation("CHECK", args); err != nil {
+		return err
+	}
+	if p.fails("CHECK") {
+		return p.Error
+	}
+	return nil
+}
+
+func (p *StubPlugin) cmdDel(args *skel.CmdArgs) error {
+	if err := recordInvocation("DEL", args); err != nil {
+		return err
+	}
+	if p.fails("DEL") {
+		return p.Error
+	}
+	return nil
+}
+
+// recordInvocation appends the invocation to the plugin's log so that tests
+// can assert which commands were executed
+func recordInvocation(command string, args *skel.CmdArgs) error {
+	data, err := json.Marshal(&Invocation{
+		Command:     command,
+		ContainerID: args.ContainerID,
+		IfName:      args.IfName,
+		Netns:       args.Netns,
+		Args:        args.Args,
+		StdinData:   string(args.StdinData),
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(os.Args[0]+stubLogSuffix, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,306 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package devinfogc removes Device Information files left behind by Device
+// Plugins and CNIs that did not clean up after themselves.
+package devinfogc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+)
+
+// DefaultMinAge is the age below which Device Information is never collected
+const DefaultMinAge = 10 * time.Minute
+
+// LiveDeviceIDsFunc returns the IDs of the devices currently allocated on the
+// node, e.g. the PCI addresses known to a Device Plugin
+type LiveDeviceIDsFunc func(ctx context.Context) ([]string, error)
+
+// Options configures a Collector
+type Options struct {
+	// Store holds the Device Information to collect. Defaults to
+	// utils.DefaultDeviceInfoStore().
+	Store utils.DeviceInfoStore
+	// Client is used to list the pods running on NodeName
+	Client kubernetes.Interface
+	// NodeName is the node whose pods keep Device Information alive
+	NodeName string
+	// LiveDeviceIDs optionally returns device IDs that keep Device
+	// Information alive even if no pod references them yet
+	LiveDeviceIDs LiveDeviceIDsFunc
+	// ResourceNames are the Device Plugin resource names. Device Plugin
+	// Information whose filename starts with one of them is kept if the
+	// device ID in the filename is in use, even if it cannot be read; other
+	// files are only matched by content.
+	ResourceNames []string
+	// MinAge protects Device Information younger than this. Defaults to DefaultMinAge.
+	MinAge time.Duration
+	// DryRun reports orphans without removing them
+	DryRun bool
+	// Out receives one line per orphan. Defaults to ioutil.Discard.
+	Out io.Writer
+}
+
+// Orphan is Device Information no longer referenced by a pod or a live device
+type Orphan struct {
+	utils.DeviceInfoEntry
+	// Reason explains why the entry is considered orphaned
+	Reason string
+}
+
+// Collector finds and removes orphaned Device Information
+type Collector struct {
+	opts Options
+	now  func() time.Time
+}
+
+// NewCollector returns a Collector for the given options
+func NewCollector(opts Options) (*Collector, error) {
+	if opts.Client == nil {
+		return nil, fmt.Errorf("no client set")
+	}
+	if opts.NodeName == "" {
+		return nil, fmt.Errorf("no node name set")
+	}
+	if opts.Store == nil {
+		opts.Store = utils.DefaultDeviceInfoStore()
+	}
+	if opts.MinAge == 0 {
+		opts.MinAge = DefaultMinAge
+	}
+	if opts.Out == nil {
+		opts.Out = ioutil.Discard
+	}
+	return &Collector{opts: opts, now: time.Now}, nil
+}
+
+// Collect removes all orphaned Device Information older than the minimum
+// age, or only reports it in dry-run mode, and returns the orphans found
+func (c *Collector) Collect(ctx context.Context) ([]Orphan, error) {
+	entries, err := c.opts.Store.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list device information: %v", err)
+	}
+
+	podIDs, err := c.podDeviceIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	liveIDs := sets.NewString()
+	if c.opts.LiveDeviceIDs != nil {
+		ids, err := c.opts.LiveDeviceIDs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get live device IDs: %v", err)
+		}
+		liveIDs.Insert(ids...)
+	}
+
+	var orphans []Orphan
+	var errs []string
+	for _, entry := range entries {
+		if c.now().Sub(entry.ModTime) < c.opts.MinAge {
+			continue
+		}
+
+		reason, orphaned, err := c.isOrphaned(entry, podIDs, liveIDs)
+		if err != nil {
+			// Unreadable Device Information, e.g. of a newer version, may
+			// still be used: it is never collected
+			glog.Warningf("skipping device information %s: %v", entry.Path, err)
+			fmt.Fprintf(c.opts.Out, "skipped %s: %v\n", entry.Path, err)
+			continue
+		}
+		if !orphaned {
+			continue
+		}
+		orphan := Orphan{DeviceInfoEntry: entry, Reason: reason}
+		orphans = append(orphans, orphan)
+
+		if c.opts.DryRun {
+			fmt.Fprintf(c.opts.Out, "would remove %s: %s\n", entry.Path, reason)
+			continue
+		}
+		if err := c.opts.Store.Clean(entry.Path); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", entry.Path, err))
+			continue
+		}
+		fmt.Fprintf(c.opts.Out, "removed %s: %s\n", entry.Path, reason)
+	}
+
+	if len(errs) > 0 {
+		return orphans, fmt.Errorf("failed to remove device information: %s", strings.Join(errs, "; "))
+	}
+	return orphans, nil
+}
+
+// Run collects orphaned Device Information every interval until ctx is done
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.Collect(ctx); err != nil {
+			glog.Errorf("device information garbage collection failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// isOrphaned reports whether entry is referenced neither by a pod nor by a
+// live device. Device Information that cannot be loaded is reported with an
+// error, unless the device ID in its filename is referenced.
+func (c *Collector) isOrphaned(entry utils.DeviceInfoEntry, podIDs, liveIDs sets.String) (string, bool, error) {
+	if entry.Owner == utils.DeviceInfoOwnerDP {
+		if deviceID, ok := c.dpFilenameDeviceID(entry.Path); ok &&
+			(hasFilenameID(podIDs, deviceID) || hasFilenameID(liveIDs, deviceID)) {
+			return "", false, nil
+		}
+	}
+
+	devInfo, err := c.opts.Store.Load(entry.Path)
+	if os.IsNotExist(err) {
+		// Cleaned up by its owner since it was listed
+		return "", false, nil
+	} else if err != nil {
+		return "", false, fmt.Errorf("unreadable device information: %v", err)
+	}
+
+	ids := DeviceIDs(devInfo)
+	if podIDs.HasAny(ids...) || liveIDs.HasAny(ids...) {
+		return "", false, nil
+	}
+	return fmt.Sprintf("%s device %s is not used by any pod on node %s", devInfo.Type, strings.Join(ids, ","), c.opts.NodeName), true, nil
+}
+
+// dpFilenameDeviceID returns the device ID of a Device Plugin filename of one
+// of the configured resources. Without a known resource name, the device ID
+// cannot be told apart from a resource name containing dashes.
+func (c *Collector) dpFilenameDeviceID(path string) (string, bool) {
+	resourceName, deviceID, err := utils.ParseDPDeviceInfoFilename(path, c.opts.ResourceNames...)
+	if err != nil {
+		return "", false
+	}
+	for _, name := range c.opts.ResourceNames {
+		if name == resourceName {
+			return deviceID, true
+		}
+	}
+	return "", false
+}
+
+// hasFilenameID returns true if one of ids is deviceID as written in a
+// Device Plugin filename, where slashes are replaced by dashes
+func hasFilenameID(ids sets.String, deviceID string) bool {
+	for id := range ids {
+		if strings.ReplaceAll(id, "/", "-") == deviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// podDeviceIDs returns the device IDs referenced by the network status of
+// the running pods on the node. It fails if the network status of one of
+// them cannot be parsed, since the devices it uses are then unknown.
+func (c *Collector) podDeviceIDs(ctx context.Context) (sets.String, error) {
+	pods, err := c.opts.Client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", c.opts.NodeName).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods on node %s: %v", c.opts.NodeName, err)
+	}
+
+	ids := sets.NewString()
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName != c.opts.NodeName ||
+			pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		_, ok := pod.Annotations[v1.NetworkStatusAnnot]
+		if !ok {
+			_, ok = pod.Annotations[v1.LegacyNetworkStatusAnnot]
+		}
+		if !ok {
+			continue
+		}
+		statuses, err := utils.GetNetworkStatus(pod)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the network status of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+		for _, status := range statuses {
+			ids.Insert(DeviceIDs(status.DeviceInfo)...)
+		}
+	}
+	return ids, nil
+}
+
+// DeviceIDs returns the identifiers of the device described by devInfo:
+// PCI addresses, auxiliary and network device names, and socket or device paths
+func DeviceIDs(devInfo *v1.DeviceInfo) []string {
+	if devInfo == nil {
+		return nil
+	}
+
+	var ids []string
+	add := func(id string) {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if devInfo.Pci != nil {
+		add(devInfo.Pci.PciAddress)
+	}
+	if devInfo.Vdpa != nil {
+		add(devInfo.Vdpa.PciAddress)
+		add(devInfo.Vdpa.Path)
+	}
+	if devInfo.VhostUser != nil {
+		add(devInfo.VhostUser.Path)
+	}
+	if devInfo.Memif != nil {
+		add(devInfo.Memif.Path)
+	}
+	if devInfo.Auxiliary != nil {
+		add(devInfo.Auxiliary.DeviceName)
+	}
+	if devInfo.AFXDP != nil {
+		add(devInfo.AFXDP.Netdev)
+		add(devInfo.AFXDP.Path)
+	}
+	return ids
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package devinfogc
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func pciDeviceInfo(address string) *v1.DeviceInfo {
+	return &v1.DeviceInfo{
+		Type:    v1.DeviceInfoTypePCI,
+		Version: v1.DeviceInfoVersion,
+		Pci:     &v1.PciDevice{PciAddress: address},
+	}
+}
+
+func podWithDevice(name, nodeName, address string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Annotations: map[string]string{
+				v1.NetworkStatusAnnot: `[{"name": "default/sriov", "interface": "net1", "device-info": {"type": "pci", "version": "1.0.0", "pci": {"pci-address": "` + address + `"}}}]`,
+			},
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+var _ = Describe("Device Information garbage collection", func() {
+	const nodeName = "node1"
+	const resourceName = "example.com/sriov"
+
+	var store *utils.MemDeviceInfoStore
+	var out *bytes.Buffer
+	var later time.Time
+
+	BeforeEach(func() {
+		store = utils.NewMemDeviceInfoStore()
+		out = &bytes.Buffer{}
+		later = time.Now().Add(time.Hour)
+
+		for _, address := range []string{"0000:01:00.1", "0000:01:00.2", "0000:01:00.3", "0000:01:00.4"} {
+			Expect(store.Save(store.DPDeviceInfoPath(resourceName, address), pciDeviceInfo(address))).To(Succeed())
+		}
+		Expect(store.Save(store.CNIDeviceInfoPath("container1-net1"), pciDeviceInfo("0000:01:00.1"))).To(Succeed())
+		Expect(store.Save(store.CNIDeviceInfoPath("container2-net1"), pciDeviceInfo("0000:01:00.2"))).To(Succeed())
+	})
+
+	collector := func(opts Options) *Collector {
+		if opts.Store == nil {
+			opts.Store = store
+		}
+		opts.NodeName = nodeName
+		opts.Out = out
+		opts.Client = fake.NewSimpleClientset(
+			podWithDevice("pod1", nodeName, "0000:01:00.1"),
+			// Pods on other nodes do not keep local devices alive
+			podWithDevice("pod2", "node2", "0000:01:00.2"),
+		)
+		c, err := NewCollector(opts)
+		Expect(err).NotTo(HaveOccurred())
+		c.now = func() time.Time { return later }
+		return c
+	}
+
+	It("removes device information not used by any pod or live device", func() {
+		c := collector(Options{
+			LiveDeviceIDs: func(context.Context) ([]string, error) { return []string{"0000:01:00.3"}, nil },
+		})
+
+		orphans, err := c.Collect(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+
+		var paths []string
+		for _, o := range orphans {
+			paths = append(paths, o.Path)
+		}
+		Expect(paths).To(ConsistOf(
+			store.CNIDeviceInfoPath("container2-net1"),
+			store.DPDeviceInfoPath(resourceName, "0000:01:00.2"),
+			store.DPDeviceInfoPath(resourceName, "0000:01:00.4"),
+		))
+
+		entries, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(3))
+		Expect(out.String()).To(ContainSubstring("removed " + store.DPDeviceInfoPath(resourceName, "0000:01:00.4")))
+	})
+
+	It("only reports orphans in dry-run mode", func() {
+		c := collector(Options{DryRun: true})
+
+		orphans, err := c.Collect(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(HaveLen(4))
+
+		entries, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(6))
+		Expect(out.String()).To(ContainSubstring("would remove " + store.DPDeviceInfoPath(resourceName, "0000:01:00.3")))
+	})
+
+	It("keeps device information younger than the minimum age", func() {
+		c := collector(Options{MinAge: 2 * time.Hour})
+
+		orphans, err := c.Collect(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(BeEmpty())
+	})
+
+	It("matches live device IDs to Device Plugin filenames exactly", func() {
+		Expect(store.Save(store.DPDeviceInfoPath(resourceName, "vf-4"), pciDeviceInfo("0000:02:00.4"))).To(Succeed())
+		c := collector(Options{
+			ResourceNames: []string{resourceName},
+			LiveDeviceIDs: func(context.Context) ([]string, error) { return []string{"4"}, nil },
+		})
+
+		orphans, err := c.Collect(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+		var paths []string
+		for _, o := range orphans {
+			paths = append(paths, o.Path)
+		}
+		Expect(paths).To(ContainElement(store.DPDeviceInfoPath(resourceName, "vf-4")))
+	})
+
+	It("never removes device information it cannot read", func() {
+		tmpDir, err := ioutil.TempDir("", "devinfogc")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+
+		fsStore := utils.NewFSDeviceInfoStore(tmpDir, utils.WithDeviceInfoValidation())
+		newer := []byte(`{"type": "pci", "version": "2.0.0", "pci": {"pci-address": "0000:01:00.1"}}`)
+		dpPath := fsStore.DPDeviceInfoPath(resourceName, "0000:01:00.1")
+		cniPath := fsStore.CNIDeviceInfoPath("container1-net1")
+		for _, path := range []string{dpPath, cniPath} {
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(path, newer, 0644)).To(Succeed())
+		}
+		// Rejected by the validating store
+		invalidPath := fsStore.DPDeviceInfoPath(resourceName, "0000:01:00.9")
+		Expect(ioutil.WriteFile(invalidPath, []byte(`{"type": "pci", "version": "1.1.0"}`), 0644)).To(Succeed())
+
+		c := collector(Options{Store: fsStore, ResourceNames: []string{resourceName}})
+		orphans, err := c.Collect(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(BeEmpty())
+
+		for _, path := range []string{dpPath, cniPath, invalidPath} {
+			Expect(path).To(BeAnExistingFile())
+		}
+		// The Device Plugin file is kept as referenced by pod1, from its name
+		Expect(out.String()).NotTo(ContainSubstring(dpPath))
+		Expect(out.String()).To(ContainSubstring("skipped " + cniPath))
+		Expect(out.String()).To(ContainSubstring("skipped " + invalidPath))
+	})
+
+	It("does not match filenames of unknown resources by their last dash", func() {
+		// Without the resource name, "example.com-sriov-vf-4" would be read
+		// as the device ID 4 of resource example.com-sriov-vf
+		Expect(store.Save(store.DPDeviceInfoPath(resourceName, "vf-4"), pciDeviceInfo("0000:02:00.4"))).To(Succeed())
+		c := collector(Options{
+			LiveDeviceIDs: func(context.Context) ([]string, error) { return []string{"4"}, nil },
+		})
+
+		orphans, err := c.Collect(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+		var paths []string
+		for _, o := range orphans {
+			paths = append(paths, o.Path)
+		}
+		Expect(paths).To(ContainElement(store.DPDeviceInfoPath(resourceName, "vf-4")))
+	})
+
+	It("collects nothing if the network status of a pod cannot be parsed", func() {
+		malformed := podWithDevice("pod3", nodeName, "0000:01:00.2")
+		malformed.Annotations[v1.NetworkStatusAnnot] = `[{"name": "default/sriov",`
+		c := collector(Options{})
+		c.opts.Client = fake.NewSimpleClientset(podWithDevice("pod1", nodeName, "0000:01:00.1"), malformed)
+
+		orphans, err := c.Collect(context.TODO())
+		Expect(err).To(MatchError(ContainSubstring("failed to parse the network status of pod default/pod3")))
+		Expect(orphans).To(BeEmpty())
+
+		entries, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(6))
+	})
+
+	It("requires a client and a node name", func() {
+		_, err := NewCollector(Options{NodeName: nodeName})
+		Expect(err).To(HaveOccurred())
+		_, err = NewCollector(Options{Client: fake.NewSimpleClientset()})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,211 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package devinfowatch watches the Device Information files written by
+// Device Plugins, so that CNI plugins can react to device allocation
+// without polling.
+package devinfowatch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/golang/glog"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+)
+
+// EventType is the kind of change reported by a Watcher
+type EventType string
+
+const (
+	// Added is reported for Device Information that appeared, including
+	// the files found when the Watcher starts
+	Added EventType = "ADDED"
+	// Updated is reported when the content of a file changed
+	Updated EventType = "UPDATED"
+	// Deleted is reported when a file was removed
+	Deleted EventType = "DELETED"
+)
+
+// Event is a change to the Device Information of a device
+type Event struct {
+	Type EventType
+	// Path is the Device Information file
+	Path string
+	// ResourceName and DeviceID are parsed from the filename, see
+	// utils.ParseDPDeviceInfoFilename
+	ResourceName string
+	DeviceID     string
+	// DeviceInfo is the decoded Device Information. For Deleted events it
+	// is the last content seen.
+	DeviceInfo *v1.DeviceInfo
+}
+
+// Options configures a Watcher
+type Options struct {
+	// Root is the Device Information root directory, the Watcher watches
+	// its dp subdirectory. Defaults to utils.DefaultDeviceInfoPath.
+	Root string
+	// ResourceNames are the resource names whose original form is restored
+	// when parsing filenames
+	ResourceNames []string
+	// StoreOptions are applied when decoding Device Information, e.g.
+	// utils.WithDeviceInfoValidation()
+	StoreOptions []utils.DeviceInfoStoreOption
+}
+
+// Watcher emits an Event for each Device Plugin Device Information file that
+// is added, updated or deleted
+type Watcher struct {
+	opts   Options
+	store  *utils.FSDeviceInfoStore
+	dir    string
+	events chan Event
+
+	lock  sync.Mutex
+	known map[string]*v1.DeviceInfo
+}
+
+// New returns a Watcher for the given options. Call Run to start it.
+func New(opts Options) *Watcher {
+	store := utils.NewFSDeviceInfoStore(opts.Root, opts.StoreOptions...)
+	return &Watcher{
+		opts:   opts,
+		store:  store,
+		dir:    filepath.Dir(store.DPDeviceInfoPath("resource", "device")),
+		events: make(chan Event),
+		known:  make(map[string]*v1.DeviceInfo),
+	}
+}
+
+// Events returns the channel the events are sent on. It is closed when Run
+// returns.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Get returns the Device Information last seen for a device, or nil
+func (w *Watcher) Get(resourceName, deviceID string) *v1.DeviceInfo {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.known[w.store.DPDeviceInfoPath(resourceName, deviceID)].DeepCopy()
+}
+
+// Run sends an Added event for every existing file, then watches for
+// changes until ctx is done
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.events)
+
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Watch before listing so that no file is missed in between; files seen
+	// twice are deduplicated by sync.
+	if err := watcher.Add(w.dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %v", w.dir, err)
+	}
+
+	files, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if !w.sync(ctx, filepath.Join(w.dir, file.Name())) {
+			return nil
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !w.sync(ctx, event.Name) {
+				return nil
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			glog.Warningf("devinfowatch: error watching %s: %v", w.dir, err)
+		}
+	}
+}
+
+// sync compares the file at path with what was last seen and sends the
+// matching event, if any. It returns false if ctx is done.
+func (w *Watcher) sync(ctx context.Context, path string) bool {
+	resourceName, deviceID, err := utils.ParseDPDeviceInfoFilename(path, w.opts.ResourceNames...)
+	if err != nil {
+		// temporary files and unrelated entries
+		return true
+	}
+
+	event := Event{Path: path, ResourceName: resourceName, DeviceID: deviceID}
+	devInfo, err := w.store.Load(path)
+	if err != nil && !os.IsNotExist(err) {
+		glog.Warningf("devinfowatch: %v", err)
+		return true
+	}
+
+	w.lock.Lock()
+	last, seen := w.known[path]
+	switch {
+	case devInfo == nil && seen:
+		event.Type = Deleted
+		event.DeviceInfo = last
+		delete(w.known, path)
+	case devInfo == nil:
+		w.lock.Unlock()
+		return true
+	case !seen:
+		event.Type = Added
+		event.DeviceInfo = devInfo.DeepCopy()
+		w.known[path] = devInfo
+	case !reflect.DeepEqual(last, devInfo):
+		event.Type = Updated
+		event.DeviceInfo = devInfo.DeepCopy()
+		w.known[path] = devInfo
+	default:
+		w.lock.Unlock()
+		return true
+	}
+	w.lock.Unlock()
+
+	select {
+	case w.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package devinfowatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Device Information watcher", func() {
+	var tmpDir string
+	var store *utils.FSDeviceInfoStore
+	var watcher *Watcher
+	var cancel context.CancelFunc
+	var done chan error
+
+	newDevInfo := func(pciAddress string) *v1.DeviceInfo {
+		return &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypePCI,
+			Version: v1.DeviceInfoVersion,
+			Pci:     &v1.PciDevice{PciAddress: pciAddress},
+		}
+	}
+
+	nextEvent := func() Event {
+		var event Event
+		Eventually(watcher.Events(), 5*time.Second).Should(Receive(&event))
+		return event
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "devinfowatch")
+		Expect(err).NotTo(HaveOccurred())
+		store = utils.NewFSDeviceInfoStore(tmpDir)
+	})
+
+	JustBeforeEach(func() {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		watcher = New(Options{Root: tmpDir, ResourceNames: []string{"example.com/sriov"}})
+		done = make(chan error, 1)
+		go func() {
+			done <- watcher.Run(ctx)
+		}()
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done, 5*time.Second).Should(Receive(BeNil()))
+		Expect(watcher.Events()).To(BeClosed())
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	Context("with existing device information", func() {
+		BeforeEach(func() {
+			path := store.DPDeviceInfoPath("example.com/sriov", "0000:01:02.2")
+			Expect(store.Save(path, newDevInfo("0000:01:02.2"))).To(Succeed())
+		})
+
+		It("reports it as added on start", func() {
+			event := nextEvent()
+			Expect(event.Type).To(Equal(Added))
+			Expect(event.ResourceName).To(Equal("example.com/sriov"))
+			Expect(event.DeviceID).To(Equal("0000:01:02.2"))
+			Expect(event.DeviceInfo).To(Equal(newDevInfo("0000:01:02.2")))
+			Expect(watcher.Get("example.com/sriov", "0000:01:02.2")).To(Equal(newDevInfo("0000:01:02.2")))
+			Consistently(watcher.Events(), 200*time.Millisecond).ShouldNot(Receive())
+		})
+	})
+
+	It("reports device information as it is added and deleted", func() {
+		Eventually(filepath.Join(tmpDir, "dp")).Should(BeADirectory())
+
+		path := store.DPDeviceInfoPath("example.com/sriov", "0000:01:02.3")
+		Expect(store.Save(path, newDevInfo("0000:01:02.3"))).To(Succeed())
+		event := nextEvent()
+		Expect(event.Type).To(Equal(Added))
+		Expect(event.Path).To(Equal(path))
+		Expect(event.DeviceID).To(Equal("0000:01:02.3"))
+
+		Expect(store.Clean(path)).To(Succeed())
+		event = nextEvent()
+		Expect(event.Type).To(Equal(Deleted))
+		Expect(event.DeviceInfo).To(Equal(newDevInfo("0000:01:02.3")))
+		Expect(watcher.Get("example.com/sriov", "0000:01:02.3")).To(BeNil())
+	})
+
+	It("reports changed content as an update", func() {
+		Eventually(filepath.Join(tmpDir, "dp")).Should(BeADirectory())
+
+		path := store.DPDeviceInfoPath("example.com/sriov", "0000:01:02.4")
+		Expect(store.Save(path, newDevInfo("0000:01:02.4"))).To(Succeed())
+		Expect(nextEvent().Type).To(Equal(Added))
+
+		// rewrite in place, as a Device Plugin not using the store might do
+		Expect(os.Chmod(path, 0644)).To(Succeed())
+		updated := newDevInfo("0000:01:02.4")
+		updated.Pci.PfPciAddress = "0000:01:00.0"
+		data, err := utils.EncodeDeviceInfo(updated, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(path, data, 0644)).To(Succeed())
+
+		event := nextEvent()
+		Expect(event.Type).To(Equal(Updated))
+		Expect(event.DeviceInfo.Pci.PfPciAddress).To(Equal("0000:01:00.0"))
+	})
+
+	It("ignores temporary and unrelated files", func() {
+		Eventually(filepath.Join(tmpDir, "dp")).Should(BeADirectory())
+
+		Expect(ioutil.WriteFile(filepath.Join(tmpDir, "dp", ".devinfo-123"), []byte("{"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(tmpDir, "dp", "README"), []byte("hello"), 0644)).To(Succeed())
+		Consistently(watcher.Events(), 200*time.Millisecond).ShouldNot(Receive())
+	})
+})
@@ -0,0 +1,167 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hotplug adds and removes secondary interfaces of running pods.
+//
+// The desired attachments of a pod are read from its network selection
+// annotation and the current ones from its network status annotation. The
+// difference between the two is what needs to be attached or detached.
+package hotplug
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+)
+
+// Diff lists the attachments of a pod that need to change
+type Diff struct {
+	// ToAdd are requested networks without a matching status
+	ToAdd []*v1.NetworkSelectionElement
+	// ToRemove are statuses of secondary networks no longer requested
+	ToRemove []v1.NetworkStatus
+}
+
+// Empty returns true if nothing needs to change
+func (d *Diff) Empty() bool {
+	return len(d.ToAdd) == 0 && len(d.ToRemove) == 0
+}
+
+// ComputeDiff compares the networks requested in the network selection
+// annotation of pod with its network status.
+//
+// Attachments are matched by utils.MatchNetworkStatus. The status of the
+// default network is never removed.
+func ComputeDiff(pod *corev1.Pod) (*Diff, error) {
+	if pod == nil {
+		return nil, fmt.Errorf("no pod set")
+	}
+
+	desired, err := desiredAttachments(pod)
+	if err != nil {
+		return nil, err
+	}
+	current, err := currentAttachments(pod)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &Diff{}
+	matches, unmatched := utils.MatchNetworkStatus(desired, current, pod.Namespace)
+	for _, match := range matches {
+		if match.Status == nil {
+			diff.ToAdd = append(diff.ToAdd, match.Network)
+		}
+	}
+	for _, status := range unmatched {
+		if !status.Default {
+			diff.ToRemove = append(diff.ToRemove, status)
+		}
+	}
+	return diff, nil
+}
+
+func desiredAttachments(pod *corev1.Pod) ([]*v1.NetworkSelectionElement, error) {
+	networks, err := utils.ParsePodNetworkAnnotation(pod)
+	if err != nil {
+		if _, ok := err.(*v1.NoK8sNetworkError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return networks, nil
+}
+
+func currentAttachments(pod *corev1.Pod) ([]v1.NetworkStatus, error) {
+	_, ok := pod.Annotations[v1.NetworkStatusAnnot]
+	if !ok {
+		_, ok = pod.Annotations[v1.LegacyNetworkStatusAnnot]
+	}
+	if !ok {
+		return nil, nil
+	}
+	return utils.GetNetworkStatus(pod)
+}
+
+// Attacher performs the actual attachment and detachment of networks
+type Attacher interface {
+	// Attach adds network to pod and returns the status of the new interface
+	Attach(ctx context.Context, pod *corev1.Pod, network *v1.NetworkSelectionElement) (*v1.NetworkStatus, error)
+	// Detach removes the interface described by status from pod
+	Detach(ctx context.Context, pod *corev1.Pod, status v1.NetworkStatus) error
+}
+
+// Reconciler brings the attachments of pods in line with their network
+// selection annotation
+type Reconciler struct {
+	client   kubernetes.Interface
+	attacher Attacher
+	opts     []utils.NetworkStatusOption
+}
+
+// NewReconciler returns a Reconciler recording the network status with
+// client. opts are applied when writing the network status.
+func NewReconciler(client kubernetes.Interface, attacher Attacher, opts ...utils.NetworkStatusOption) *Reconciler {
+	return &Reconciler{client: client, attacher: attacher, opts: opts}
+}
+
+// Reconcile detaches the networks no longer requested by pod and attaches
+// the new ones, updating its network status after each step. All steps are
+// attempted; the returned error aggregates the failed ones. The returned Diff
+// is the one computed before any change was made.
+func (r *Reconciler) Reconcile(ctx context.Context, pod *corev1.Pod) (*Diff, error) {
+	if r.client == nil {
+		return nil, fmt.Errorf("no client set")
+	}
+	if r.attacher == nil {
+		return nil, fmt.Errorf("no attacher set")
+	}
+
+	diff, err := ComputeDiff(pod)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, status := range diff.ToRemove {
+		if err := r.attacher.Detach(ctx, pod, status); err != nil {
+			errs = append(errs, fmt.Errorf("failed to detach %s interface %s: %v", status.Name, status.Interface, err))
+			continue
+		}
+		if err := utils.RemoveNetworkStatus(r.client, pod, status.Name, status.Interface, r.opts...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, network := range diff.ToAdd {
+		status, err := r.attacher.Attach(ctx, pod, network)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to attach %s/%s: %v", network.Namespace, network.Name, err))
+			continue
+		}
+		if status == nil {
+			continue
+		}
+		if err := utils.UpsertNetworkStatus(r.client, pod, *status, r.opts...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return diff, utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hotplug
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeAttacher struct {
+	attached []string
+	detached []string
+	failOn   string
+}
+
+func (a *fakeAttacher) Attach(ctx context.Context, pod *corev1.Pod, network *v1.NetworkSelectionElement) (*v1.NetworkStatus, error) {
+	name := network.Namespace + "/" + network.Name
+	if name == a.failOn {
+		return nil, fmt.Errorf("injected failure")
+	}
+	a.attached = append(a.attached, name)
+	iface := network.InterfaceRequest
+	if iface == "" {
+		iface = fmt.Sprintf("net%d", len(a.attached)+10)
+	}
+	return &v1.NetworkStatus{Name: name, Interface: iface}, nil
+}
+
+func (a *fakeAttacher) Detach(ctx context.Context, pod *corev1.Pod, status v1.NetworkStatus) error {
+	a.detached = append(a.detached, status.Name+"@"+status.Interface)
+	return nil
+}
+
+func newPod(networks string, statuses ...v1.NetworkStatus) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod",
+			Namespace:   "ns",
+			Annotations: map[string]string{},
+		},
+	}
+	if networks != "" {
+		pod.Annotations[v1.NetworkAttachmentAnnot] = networks
+	}
+	if statuses != nil {
+		data, err := json.Marshal(statuses)
+		Expect(err).NotTo(HaveOccurred())
+		pod.Annotations[v1.NetworkStatusAnnot] = string(data)
+	}
+	return pod
+}
+
+var _ = Describe("Network hot-plug", func() {
+	defaultStatus := v1.NetworkStatus{Name: "cbr0", Interface: "eth0", Default: true}
+
+	Context("computing the diff", func() {
+		It("is empty when the status matches the request", func() {
+			diff, err := ComputeDiff(newPod("net-a@net1,other/net-b",
+				defaultStatus,
+				v1.NetworkStatus{Name: "ns/net-a", Interface: "net1"},
+				v1.NetworkStatus{Name: "other/net-b", Interface: "net2"},
+			))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff.Empty()).To(BeTrue())
+		})
+
+		It("lists networks to add and to remove", func() {
+			diff, err := ComputeDiff(newPod("net-a@net1,net-c",
+				defaultStatus,
+				v1.NetworkStatus{Name: "ns/net-a", Interface: "net3"},
+				v1.NetworkStatus{Name: "ns/net-b", Interface: "net2"},
+			))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff.ToAdd).To(HaveLen(2))
+			Expect(diff.ToAdd[0].Name).To(Equal("net-a"))
+			Expect(diff.ToAdd[0].InterfaceRequest).To(Equal("net1"))
+			Expect(diff.ToAdd[1].Name).To(Equal("net-c"))
+			Expect(diff.ToRemove).To(Equal([]v1.NetworkStatus{
+				{Name: "ns/net-a", Interface: "net3"},
+				{Name: "ns/net-b", Interface: "net2"},
+			}))
+		})
+
+		It("matches requests naming an interface first", func() {
+			diff, err := ComputeDiff(newPod("net-a,net-a@net1",
+				v1.NetworkStatus{Name: "ns/net-a", Interface: "net1"},
+				v1.NetworkStatus{Name: "net-a", Interface: "net2"},
+			))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff.Empty()).To(BeTrue())
+		})
+
+		It("removes all secondary networks once none is requested", func() {
+			diff, err := ComputeDiff(newPod("", defaultStatus, v1.NetworkStatus{Name: "ns/net-a", Interface: "net1"}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff.ToAdd).To(BeEmpty())
+			Expect(diff.ToRemove).To(Equal([]v1.NetworkStatus{{Name: "ns/net-a", Interface: "net1"}}))
+		})
+
+		It("fails on malformed annotations", func() {
+			_, err := ComputeDiff(newPod("[{"))
+			Expect(err).To(HaveOccurred())
+
+			pod := newPod("net-a")
+			pod.Annotations[v1.NetworkStatusAnnot] = "{"
+			_, err = ComputeDiff(pod)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("reconciling", func() {
+		It("attaches and detaches networks and records their status", func() {
+			pod := newPod("net-a@net1,net-c", defaultStatus, v1.NetworkStatus{Name: "ns/net-b", Interface: "net2"})
+			client := fake.NewSimpleClientset(pod)
+			attacher := &fakeAttacher{}
+
+			diff, err := NewReconciler(client, attacher).Reconcile(context.TODO(), pod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff.ToAdd).To(HaveLen(2))
+			Expect(attacher.attached).To(Equal([]string{"ns/net-a", "ns/net-c"}))
+			Expect(attacher.detached).To(Equal([]string{"ns/net-b@net2"}))
+
+			pod, err = client.CoreV1().Pods("ns").Get(context.TODO(), "pod", metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			statuses, err := utils.GetNetworkStatus(pod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(statuses).To(Equal([]v1.NetworkStatus{
+				defaultStatus,
+				{Name: "ns/net-a", Interface: "net1"},
+				{Name: "ns/net-c", Interface: "net12"},
+			}))
+
+			diff, err = ComputeDiff(pod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff.Empty()).To(BeTrue())
+		})
+
+		It("continues past failed attachments", func() {
+			pod := newPod("net-a,net-c")
+			client := fake.NewSimpleClientset(pod)
+			attacher := &fakeAttacher{failOn: "ns/net-a"}
+
+			_, err := NewReconciler(client, attacher).Reconcile(context.TODO(), pod)
+			Expect(err).To(MatchError(ContainSubstring("failed to attach ns/net-a: injected failure")))
+			Expect(attacher.attached).To(Equal([]string{"ns/net-c"}))
+		})
+	})
+})
@@ -0,0 +1,302 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package multipolicy evaluates MultiNetworkPolicies: it computes the pod
+// interfaces a policy applies to and the peers its rules allow, so that
+// enforcement agents and audit tools share one implementation.
+package multipolicy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1beta1"
+	nadlisters "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/listers/k8s.cni.cncf.io/v1"
+	listers "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/listers/k8s.cni.cncf.io/v1beta1"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+)
+
+// PodInterface is an interface of a pod on a secondary network
+type PodInterface struct {
+	// Namespace and Name identify the pod
+	Namespace string
+	Name      string
+	// Network is the namespace/name of the NetworkAttachmentDefinition
+	Network   string
+	Interface string
+	IPs       []string
+}
+
+// Peers are the peers allowed by an ingress or egress rule
+type Peers struct {
+	// All is true if the rule allows any peer
+	All bool
+	// Interfaces are the pod interfaces on the policy's networks selected by
+	// the rule
+	Interfaces []PodInterface
+	// IPBlocks are the CIDRs allowed by the rule
+	IPBlocks []v1beta1.IPBlock
+	// Ports are the ports the rule allows, all ports if empty
+	Ports []v1beta1.MultiNetworkPolicyPort
+}
+
+// Evaluation is the effect of a MultiNetworkPolicy
+type Evaluation struct {
+	Policy *v1beta1.MultiNetworkPolicy
+	// Networks are the namespace/name of the networks the policy is for
+	Networks []string
+	// MissingNetworks are the Networks without a NetworkAttachmentDefinition
+	MissingNetworks []string
+	// PolicyTypes are the effective policy types
+	PolicyTypes []v1beta1.MultiPolicyType
+	// Interfaces are the pod interfaces the policy applies to
+	Interfaces []PodInterface
+	// Ingress and Egress hold the peers allowed by each rule
+	Ingress []Peers
+	Egress  []Peers
+}
+
+// PolicyFor returns the namespace/name of the networks listed in the
+// PolicyForAnnotation of policy. Names without a namespace are in the
+// namespace of the policy.
+func PolicyFor(policy *v1beta1.MultiNetworkPolicy) ([]string, error) {
+	annot := strings.TrimSpace(policy.Annotations[v1beta1.PolicyForAnnotation])
+	if annot == "" {
+		return nil, fmt.Errorf("policy %s/%s has no %s annotation", policy.Namespace, policy.Name, v1beta1.PolicyForAnnotation)
+	}
+
+	var networks []string
+	for _, item := range strings.Split(annot, ",") {
+		item = strings.TrimSpace(item)
+		switch parts := strings.Split(item, "/"); {
+		case len(parts) == 1 && parts[0] != "":
+			networks = append(networks, policy.Namespace+"/"+parts[0])
+		case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+			networks = append(networks, item)
+		default:
+			return nil, fmt.Errorf("policy %s/%s: invalid network %q in %s",
+				policy.Namespace, policy.Name, item, v1beta1.PolicyForAnnotation)
+		}
+	}
+	return networks, nil
+}
+
+// EffectivePolicyTypes returns the policy types of policy, defaulted as for
+// a NetworkPolicy: Ingress, plus Egress if it has egress rules
+func EffectivePolicyTypes(policy *v1beta1.MultiNetworkPolicy) []v1beta1.MultiPolicyType {
+	if len(policy.Spec.PolicyTypes) > 0 {
+		return policy.Spec.PolicyTypes
+	}
+	types := []v1beta1.MultiPolicyType{v1beta1.PolicyTypeIngress}
+	if len(policy.Spec.Egress) > 0 {
+		types = append(types, v1beta1.PolicyTypeEgress)
+	}
+	return types
+}
+
+// Evaluator evaluates MultiNetworkPolicies against the pods, namespaces and
+// NetworkAttachmentDefinitions known to its listers, typically backed by
+// shared informers
+type Evaluator struct {
+	pods       corelisters.PodLister
+	namespaces corelisters.NamespaceLister
+	nads       nadlisters.NetworkAttachmentDefinitionLister
+	policies   listers.MultiNetworkPolicyLister
+}
+
+// NewEvaluator returns an Evaluator using the given listers
+func NewEvaluator(pods corelisters.PodLister, namespaces corelisters.NamespaceLister,
+	nads nadlisters.NetworkAttachmentDefinitionLister, policies listers.MultiNetworkPolicyLister) *Evaluator {
+	return &Evaluator{pods: pods, namespaces: namespaces, nads: nads, policies: policies}
+}
+
+// EvaluateAll evaluates every MultiNetworkPolicy, sorted by namespace and name
+func (e *Evaluator) EvaluateAll() ([]*Evaluation, error) {
+	policies, err := e.policies.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list policies: %v", err)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		if policies[i].Namespace != policies[j].Namespace {
+			return policies[i].Namespace < policies[j].Namespace
+		}
+		return policies[i].Name < policies[j].Name
+	})
+
+	var evaluations []*Evaluation
+	for _, policy := range policies {
+		evaluation, err := e.Evaluate(policy)
+		if err != nil {
+			return nil, err
+		}
+		evaluations = append(evaluations, evaluation)
+	}
+	return evaluations, nil
+}
+
+// Evaluate computes the pod interfaces policy applies to and the peers
+// allowed by its rules. Only interfaces on the networks of the policy are
+// considered, both for the targets and the peers.
+func (e *Evaluator) Evaluate(policy *v1beta1.MultiNetworkPolicy) (*Evaluation, error) {
+	networks, err := PolicyFor(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation := &Evaluation{
+		Policy:      policy,
+		Networks:    networks,
+		PolicyTypes: EffectivePolicyTypes(policy),
+	}
+
+	for _, network := range networks {
+		parts := strings.SplitN(network, "/", 2)
+		if _, err := e.nads.NetworkAttachmentDefinitions(parts[0]).Get(parts[1]); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get network attachment definition %s: %v", network, err)
+			}
+			evaluation.MissingNetworks = append(evaluation.MissingNetworks, network)
+		}
+	}
+
+	podSelector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s/%s: invalid pod selector: %v", policy.Namespace, policy.Name, err)
+	}
+	if evaluation.Interfaces, err = e.interfaces([]string{policy.Namespace}, podSelector, networks); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range policy.Spec.Ingress {
+		peers, err := e.peers(policy, rule.From, rule.Ports, networks)
+		if err != nil {
+			return nil, err
+		}
+		evaluation.Ingress = append(evaluation.Ingress, *peers)
+	}
+	for _, rule := range policy.Spec.Egress {
+		peers, err := e.peers(policy, rule.To, rule.Ports, networks)
+		if err != nil {
+			return nil, err
+		}
+		evaluation.Egress = append(evaluation.Egress, *peers)
+	}
+	return evaluation, nil
+}
+
+func (e *Evaluator) peers(policy *v1beta1.MultiNetworkPolicy, from []v1beta1.MultiNetworkPolicyPeer,
+	ports []v1beta1.MultiNetworkPolicyPort, networks []string) (*Peers, error) {
+	peers := &Peers{All: len(from) == 0, Ports: ports}
+
+	for _, peer := range from {
+		if peer.IPBlock != nil {
+			peers.IPBlocks = append(peers.IPBlocks, *peer.IPBlock)
+			continue
+		}
+
+		namespaces := []string{policy.Namespace}
+		if peer.NamespaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(peer.NamespaceSelector)
+			if err != nil {
+				return nil, fmt.Errorf("policy %s/%s: invalid namespace selector: %v", policy.Namespace, policy.Name, err)
+			}
+			list, err := e.namespaces.List(selector)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list namespaces: %v", err)
+			}
+			namespaces = namespaces[:0]
+			for _, ns := range list {
+				namespaces = append(namespaces, ns.Name)
+			}
+		}
+
+		podSelector := labels.Everything()
+		if peer.PodSelector != nil {
+			var err error
+			if podSelector, err = metav1.LabelSelectorAsSelector(peer.PodSelector); err != nil {
+				return nil, fmt.Errorf("policy %s/%s: invalid pod selector: %v", policy.Namespace, policy.Name, err)
+			}
+		}
+
+		interfaces, err := e.interfaces(namespaces, podSelector, networks)
+		if err != nil {
+			return nil, err
+		}
+		peers.Interfaces = append(peers.Interfaces, interfaces...)
+	}
+	return peers, nil
+}
+
+// interfaces returns the interfaces on networks of the running pods of
+// namespaces matching selector, sorted by pod and interface
+func (e *Evaluator) interfaces(namespaces []string, selector labels.Selector, networks []string) ([]PodInterface, error) {
+	var interfaces []PodInterface
+	for _, namespace := range namespaces {
+		pods, err := e.pods.Pods(namespace).List(selector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods: %v", err)
+		}
+		for _, pod := range pods {
+			if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+				continue
+			}
+			statuses, err := utils.GetNetworkStatus(pod)
+			if err != nil {
+				// pods without a (valid) network status have no secondary interfaces yet
+				continue
+			}
+			for _, status := range statuses {
+				network := utils.CanonicalNetworkName(status.Name, pod.Namespace)
+				if status.Default || !contains(networks, network) {
+					continue
+				}
+				interfaces = append(interfaces, PodInterface{
+					Namespace: pod.Namespace,
+					Name:      pod.Name,
+					Network:   network,
+					Interface: status.Interface,
+					IPs:       status.IPs,
+				})
+			}
+		}
+	}
+
+	sort.Slice(interfaces, func(i, j int) bool {
+		a, b := interfaces[i], interfaces[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Interface < b.Interface
+	})
+	return interfaces, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multipolicy
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1beta1"
+	nadlisters "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/listers/k8s.cni.cncf.io/v1"
+	listers "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/listers/k8s.cni.cncf.io/v1beta1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func newPod(namespace, name string, labels map[string]string, statuses ...v1.NetworkStatus) *corev1.Pod {
+	data, err := json.Marshal(statuses)
+	Expect(err).NotTo(HaveOccurred())
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: map[string]string{v1.NetworkStatusAnnot: string(data)},
+		},
+	}
+}
+
+func newNamespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+var _ = Describe("MultiNetworkPolicy evaluation", func() {
+	var evaluator *Evaluator
+	var policies cache.Indexer
+
+	defaultStatus := v1.NetworkStatus{Name: "cbr0", Interface: "eth0", IPs: []string{"10.244.0.5"}, Default: true}
+
+	BeforeEach(func() {
+		pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		namespaces := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		policies = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		nads := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		Expect(nads.Add(&v1.NetworkAttachmentDefinition{ObjectMeta: metav1.ObjectMeta{Name: "storage", Namespace: "app"}})).To(Succeed())
+
+		for _, ns := range []*corev1.Namespace{
+			newNamespace("app", map[string]string{"team": "a"}),
+			newNamespace("db", map[string]string{"team": "b"}),
+		} {
+			Expect(namespaces.Add(ns)).To(Succeed())
+		}
+
+		for _, pod := range []*corev1.Pod{
+			newPod("app", "web", map[string]string{"role": "web"}, defaultStatus,
+				v1.NetworkStatus{Name: "app/storage", Interface: "net1", IPs: []string{"192.168.1.10"}},
+				v1.NetworkStatus{Name: "app/other", Interface: "net2", IPs: []string{"192.168.9.10"}}),
+			newPod("app", "worker", map[string]string{"role": "worker"}, defaultStatus,
+				v1.NetworkStatus{Name: "storage", Interface: "net1", IPs: []string{"192.168.1.11"}}),
+			newPod("db", "postgres", map[string]string{"role": "db"}, defaultStatus,
+				v1.NetworkStatus{Name: "app/storage", Interface: "net1", IPs: []string{"192.168.1.20"}}),
+		} {
+			Expect(pods.Add(pod)).To(Succeed())
+		}
+		done := newPod("app", "done", map[string]string{"role": "web"},
+			v1.NetworkStatus{Name: "app/storage", Interface: "net1", IPs: []string{"192.168.1.12"}})
+		done.Status.Phase = corev1.PodSucceeded
+		Expect(pods.Add(done)).To(Succeed())
+
+		evaluator = NewEvaluator(corelisters.NewPodLister(pods), corelisters.NewNamespaceLister(namespaces),
+			nadlisters.NewNetworkAttachmentDefinitionLister(nads), listers.NewMultiNetworkPolicyLister(policies))
+	})
+
+	It("parses the policy-for annotation", func() {
+		networks, err := PolicyFor(&v1beta1.MultiNetworkPolicy{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "app",
+			Annotations: map[string]string{v1beta1.PolicyForAnnotation: "storage, db/backend"},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networks).To(Equal([]string{"app/storage", "db/backend"}))
+
+		_, err = PolicyFor(&v1beta1.MultiNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "app"}})
+		Expect(err).To(MatchError(ContainSubstring("has no k8s.v1.cni.cncf.io/policy-for annotation")))
+		_, err = PolicyFor(&v1beta1.MultiNetworkPolicy{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "app",
+			Annotations: map[string]string{v1beta1.PolicyForAnnotation: "a/b/c"},
+		}})
+		Expect(err).To(MatchError(ContainSubstring(`invalid network "a/b/c"`)))
+	})
+
+	It("computes the interfaces a policy applies to and the peers it allows", func() {
+		policy := &v1beta1.MultiNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "allow-db",
+				Namespace:   "app",
+				Annotations: map[string]string{v1beta1.PolicyForAnnotation: "storage,db/backend"},
+			},
+			Spec: v1beta1.MultiNetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"role": "web"}},
+				Ingress: []v1beta1.MultiNetworkPolicyIngressRule{{
+					From: []v1beta1.MultiNetworkPolicyPeer{
+						{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}},
+						{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "worker"}}},
+						{IPBlock: &v1beta1.IPBlock{CIDR: "192.168.1.0/24", Except: []string{"192.168.1.128/25"}}},
+					},
+				}},
+				Egress: []v1beta1.MultiNetworkPolicyEgressRule{{}},
+			},
+		}
+		Expect(policies.Add(policy)).To(Succeed())
+
+		evaluations, err := evaluator.EvaluateAll()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(evaluations).To(HaveLen(1))
+		evaluation := evaluations[0]
+
+		Expect(evaluation.Networks).To(Equal([]string{"app/storage", "db/backend"}))
+		Expect(evaluation.MissingNetworks).To(Equal([]string{"db/backend"}))
+		Expect(evaluation.PolicyTypes).To(Equal([]v1beta1.MultiPolicyType{v1beta1.PolicyTypeIngress, v1beta1.PolicyTypeEgress}))
+		Expect(evaluation.Interfaces).To(Equal([]PodInterface{
+			{Namespace: "app", Name: "web", Network: "app/storage", Interface: "net1", IPs: []string{"192.168.1.10"}},
+		}))
+
+		Expect(evaluation.Ingress).To(HaveLen(1))
+		ingress := evaluation.Ingress[0]
+		Expect(ingress.All).To(BeFalse())
+		Expect(ingress.Interfaces).To(Equal([]PodInterface{
+			{Namespace: "db", Name: "postgres", Network: "app/storage", Interface: "net1", IPs: []string{"192.168.1.20"}},
+			{Namespace: "app", Name: "worker", Network: "app/storage", Interface: "net1", IPs: []string{"192.168.1.11"}},
+		}))
+		Expect(ingress.IPBlocks).To(HaveLen(1))
+
+		Expect(evaluation.Egress).To(HaveLen(1))
+		Expect(evaluation.Egress[0].All).To(BeTrue())
+	})
+
+	It("fails on policies without networks", func() {
+		_, err := evaluator.Evaluate(&v1beta1.MultiNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "app"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,253 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nadaudit finds NetworkAttachmentDefinitions whose IPAM ranges
+// overlap, which leads to duplicate addresses when they share a link.
+package nadaudit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	listers "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/listers/k8s.cni.cncf.io/v1"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+)
+
+// FindingKind classifies a Finding
+type FindingKind string
+
+const (
+	// Overlap is reported for overlapping IPAM ranges of networks on
+	// different or unknown links
+	Overlap FindingKind = "Overlap"
+	// Conflict is reported for overlapping IPAM ranges of networks on the
+	// same link, which can hand out the same address twice
+	Conflict FindingKind = "Conflict"
+	// InvalidConfig is reported for networks whose configuration cannot be
+	// analyzed
+	InvalidConfig FindingKind = "InvalidConfig"
+)
+
+// Link is the host link a network attaches pods to
+type Link struct {
+	// Type is the CNI plugin type, e.g. macvlan or bridge
+	Type   string
+	Master string
+	Bridge string
+	VLAN   int
+}
+
+// IsZero returns true if the link is unknown
+func (l Link) IsZero() bool {
+	return l.Master == "" && l.Bridge == ""
+}
+
+// Same returns true if both links are known and attach to the same L2 domain
+func (l Link) Same(other Link) bool {
+	return !l.IsZero() && l.Master == other.Master && l.Bridge == other.Bridge && l.VLAN == other.VLAN
+}
+
+func (l Link) String() string {
+	var parts []string
+	if l.Master != "" {
+		parts = append(parts, "master "+l.Master)
+	}
+	if l.Bridge != "" {
+		parts = append(parts, "bridge "+l.Bridge)
+	}
+	if l.VLAN != 0 {
+		parts = append(parts, fmt.Sprintf("vlan %d", l.VLAN))
+	}
+	if len(parts) == 0 {
+		return "unknown link"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Network is the part of a NetworkAttachmentDefinition relevant to the audit
+type Network struct {
+	// Name is namespace/name of the NetworkAttachmentDefinition
+	Name string
+	Link Link
+	IPAM *utils.IPAMConfig
+}
+
+// Finding is a problem found by the audit
+type Finding struct {
+	Kind FindingKind
+	// Networks are the namespace/name of the networks involved
+	Networks []string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Kind, f.Message)
+}
+
+// Audit analyzes the NetworkAttachmentDefinitions known to lister
+func Audit(lister listers.NetworkAttachmentDefinitionLister) ([]Finding, error) {
+	nads, err := lister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network attachment definitions: %v", err)
+	}
+	return Analyze(nads), nil
+}
+
+// Analyze reports the overlapping IPAM ranges of nads. Ranges are compared
+// from their start to their end address, ignoring excluded subnets.
+// NetworkAttachmentDefinitions with an empty or whitespace-only Spec are
+// skipped since their configuration lives on the nodes.
+func Analyze(nads []*v1.NetworkAttachmentDefinition) []Finding {
+	var findings []Finding
+	var networks []*Network
+
+	for _, nad := range nads {
+		if utils.IsEmptySpecConfig(nad.Spec.Config) {
+			continue
+		}
+		network, err := ParseNetwork(nad)
+		if err != nil {
+			name := nad.Namespace + "/" + nad.Name
+			findings = append(findings, Finding{
+				Kind:     InvalidConfig,
+				Networks: []string{name},
+				Message:  fmt.Sprintf("%s: %v", name, err),
+			})
+			continue
+		}
+		if network.IPAM != nil && len(network.IPAM.Ranges) > 0 {
+			networks = append(networks, network)
+		}
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+
+	for i, a := range networks {
+		for _, b := range networks[i+1:] {
+			findings = append(findings, compare(a, b)...)
+		}
+	}
+	return findings
+}
+
+func compare(a, b *Network) []Finding {
+	var findings []Finding
+	for i := range a.IPAM.Ranges {
+		for j := range b.IPAM.Ranges {
+			ra, rb := &a.IPAM.Ranges[i], &b.IPAM.Ranges[j]
+			if !overlaps(ra, rb) {
+				continue
+			}
+			finding := Finding{
+				Kind:     Overlap,
+				Networks: []string{a.Name, b.Name},
+				Message: fmt.Sprintf("range %s of %s overlaps range %s of %s",
+					formatRange(ra), a.Name, formatRange(rb), b.Name),
+			}
+			if a.Link.Same(b.Link) {
+				finding.Kind = Conflict
+				finding.Message += " on " + a.Link.String()
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// bounds returns the first and last address of r in 16-byte form
+func bounds(r *utils.IPAMRange) (net.IP, net.IP) {
+	first := r.Subnet.IP.Mask(r.Subnet.Mask)
+	last := make(net.IP, len(first))
+	for i := range first {
+		last[i] = first[i] | ^r.Subnet.Mask[i]
+	}
+	if r.RangeStart != nil {
+		first = r.RangeStart
+	}
+	if r.RangeEnd != nil {
+		last = r.RangeEnd
+	}
+	return first.To16(), last.To16()
+}
+
+func overlaps(a, b *utils.IPAMRange) bool {
+	if (a.Subnet.IP.To4() == nil) != (b.Subnet.IP.To4() == nil) {
+		return false
+	}
+	aFirst, aLast := bounds(a)
+	bFirst, bLast := bounds(b)
+	return bytes.Compare(aFirst, bLast) <= 0 && bytes.Compare(bFirst, aLast) <= 0
+}
+
+func formatRange(r *utils.IPAMRange) string {
+	if r.RangeStart == nil && r.RangeEnd == nil {
+		return r.Subnet.String()
+	}
+	first, last := bounds(r)
+	return fmt.Sprintf("%s-%s", first, last)
+}
+
+// linkConf holds the link fields of the common main plugins
+type linkConf struct {
+	Type   string `json:"type"`
+	Master string `json:"master"`
+	Bridge string `json:"bridge"`
+	VLAN   int    `json:"vlan"`
+	VLANID int    `json:"vlanId"`
+}
+
+// ParseNetwork extracts the link and IPAM ranges of a
+// NetworkAttachmentDefinition. The link is taken from the first plugin of a
+// configuration list.
+func ParseNetwork(nad *v1.NetworkAttachmentDefinition) (*Network, error) {
+	config := []byte(nad.Spec.Config)
+
+	var raw struct {
+		linkConf
+		Plugins []linkConf `json:"plugins"`
+	}
+	if err := json.Unmarshal(config, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CNI config: %v", err)
+	}
+	conf := raw.linkConf
+	if len(raw.Plugins) > 0 {
+		conf = raw.Plugins[0]
+	}
+
+	ipam, err := utils.ParseIPAMConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	network := &Network{
+		Name: nad.Namespace + "/" + nad.Name,
+		Link: Link{
+			Type:   conf.Type,
+			Master: conf.Master,
+			Bridge: conf.Bridge,
+			VLAN:   conf.VLAN,
+		},
+		IPAM: ipam,
+	}
+	if conf.VLANID != 0 {
+		network.Link.VLAN = conf.VLANID
+	}
+	return network, nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nadaudit
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	listers "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/client/listers/k8s.cni.cncf.io/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func newNAD(namespace, name, config string) *v1.NetworkAttachmentDefinition {
+	return &v1.NetworkAttachmentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       v1.NetworkAttachmentDefinitionSpec{Config: config},
+	}
+}
+
+func macvlan(master string, ipam string) string {
+	return fmt.Sprintf(`{"cniVersion": "0.4.0", "type": "macvlan", "master": %q, "ipam": %s}`, master, ipam)
+}
+
+var _ = Describe("NetworkAttachmentDefinition audit", func() {
+	It("reports overlapping ranges on the same link as conflicts", func() {
+		findings := Analyze([]*v1.NetworkAttachmentDefinition{
+			newNAD("team-b", "net", macvlan("eth1", `{"type": "whereabouts", "range": "10.1.0.128/25"}`)),
+			newNAD("team-a", "net", macvlan("eth1", `{"type": "host-local", "subnet": "10.1.0.0/24"}`)),
+		})
+		Expect(findings).To(Equal([]Finding{{
+			Kind:     Conflict,
+			Networks: []string{"team-a/net", "team-b/net"},
+			Message:  "range 10.1.0.0/24 of team-a/net overlaps range 10.1.0.128/25 of team-b/net on master eth1",
+		}}))
+	})
+
+	It("reports overlapping ranges on other links as overlaps", func() {
+		findings := Analyze([]*v1.NetworkAttachmentDefinition{
+			newNAD("ns", "a", macvlan("eth1", `{"type": "host-local", "subnet": "10.1.0.0/24"}`)),
+			newNAD("ns", "b", `{"cniVersion": "0.4.0", "plugins": [{"type": "vlan", "master": "eth1", "vlanId": 100,
+				"ipam": {"type": "host-local", "subnet": "10.1.0.0/16"}}]}`),
+		})
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].Kind).To(Equal(Overlap))
+	})
+
+	It("honors range boundaries and address families", func() {
+		findings := Analyze([]*v1.NetworkAttachmentDefinition{
+			newNAD("ns", "a", macvlan("eth1", `{"type": "host-local", "ranges": [[{"subnet": "10.1.0.0/24", "rangeStart": "10.1.0.10", "rangeEnd": "10.1.0.19"}]]}`)),
+			newNAD("ns", "b", macvlan("eth1", `{"type": "whereabouts", "range": "10.1.0.20-10.1.0.29/24"}`)),
+			newNAD("ns", "c", macvlan("eth1", `{"type": "host-local", "subnet": "2001:db8::/64"}`)),
+			newNAD("ns", "d", macvlan("eth1", `{"type": "static"}`)),
+			newNAD("ns", "e", ""),
+		})
+		Expect(findings).To(BeEmpty())
+
+		findings = Analyze([]*v1.NetworkAttachmentDefinition{
+			newNAD("ns", "a", macvlan("eth1", `{"type": "host-local", "ranges": [[{"subnet": "10.1.0.0/24", "rangeStart": "10.1.0.10", "rangeEnd": "10.1.0.20"}]]}`)),
+			newNAD("ns", "b", macvlan("eth1", `{"type": "whereabouts", "range": "10.1.0.20-10.1.0.29/24"}`)),
+		})
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].Message).To(Equal("range 10.1.0.10-10.1.0.20 of ns/a overlaps range 10.1.0.20-10.1.0.29 of ns/b on master eth1"))
+	})
+
+	It("reports configurations it cannot analyze", func() {
+		findings := Analyze([]*v1.NetworkAttachmentDefinition{
+			newNAD("ns", "a", `{"type": "bridge", "ipam": {"type": "host-local", "subnet": "10.1.0.0/33"}}`),
+		})
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].Kind).To(Equal(InvalidConfig))
+		Expect(findings[0].Networks).To(Equal([]string{"ns/a"}))
+	})
+
+	It("audits the network attachment definitions of a lister", func() {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		Expect(indexer.Add(newNAD("ns", "a", `{"type": "bridge", "bridge": "br0", "ipam": {"type": "host-local", "subnet": "10.1.0.0/24"}}`))).To(Succeed())
+		Expect(indexer.Add(newNAD("ns", "b", `{"type": "bridge", "bridge": "br0", "ipam": {"type": "host-local", "subnet": "10.1.0.0/24"}}`))).To(Succeed())
+
+		findings, err := Audit(listers.NewNetworkAttachmentDefinitionLister(indexer))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].String()).To(Equal("Conflict: range 10.1.0.0/24 of ns/a overlaps range 10.1.0.0/24 of ns/b on bridge br0"))
+	})
+})
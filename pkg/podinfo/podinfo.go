@@ -0,0 +1,97 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package podinfo reads the network status of a pod from inside the pod,
+// through the annotations file of a downward API volume, so that workloads
+// need no access to the API server.
+package podinfo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
+)
+
+// DefaultAnnotationsPath is where the pod annotations are commonly
+// projected by a downward API volume
+const DefaultAnnotationsPath = "/etc/podinfo/annotations"
+
+// ParseAnnotations parses the content of a downward API annotations file,
+// made of key="escaped value" lines
+func ParseAnnotations(data []byte) (map[string]string, error) {
+	annotations := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		i := strings.Index(text, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: expected key=\"value\"", line)
+		}
+		value, err := strconv.Unquote(text[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid value for %s: %v", line, text[:i], err)
+		}
+		annotations[text[:i]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return annotations, nil
+}
+
+// ReadAnnotations reads a downward API annotations file, at
+// DefaultAnnotationsPath if path is empty
+func ReadAnnotations(path string) (map[string]string, error) {
+	if path == "" {
+		path = DefaultAnnotationsPath
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	annotations, err := ParseAnnotations(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return annotations, nil
+}
+
+// NetworkStatus decodes the network status found in annotations, falling
+// back to the legacy annotation like utils.GetNetworkStatus
+func NetworkStatus(annotations map[string]string) ([]v1.NetworkStatus, error) {
+	return utils.GetNetworkStatus(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+}
+
+// ReadNetworkStatus reads the network status from a downward API annotations
+// file, at DefaultAnnotationsPath if path is empty
+func ReadNetworkStatus(path string) ([]v1.NetworkStatus, error) {
+	annotations, err := ReadAnnotations(path)
+	if err != nil {
+		return nil, err
+	}
+	return NetworkStatus(annotations)
+}
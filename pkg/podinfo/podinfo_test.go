@@ -0,0 +1,110 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podinfo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// networkStatus is a network status annotation value as written by Multus
+const networkStatus = `[{
+    "name": "cbr0",
+    "interface": "eth0",
+    "ips": [
+        "10.244.1.2"
+    ],
+    "default": true
+},{
+    "name": "default/net-a",
+    "interface": "net1",
+    "ips": [
+        "1.1.1.1"
+    ],
+    "mac": "ee:ee:ee:ee:ee:ee"
+}]`
+
+// formatAnnotations formats annotations the way the kubelet writes them to a
+// downward API volume
+func formatAnnotations(annotations map[string]string) []byte {
+	var data []byte
+	for k, v := range annotations {
+		data = append(data, fmt.Sprintf("%v=%q\n", k, v)...)
+	}
+	return data
+}
+
+var _ = Describe("Downward API annotations", func() {
+	It("parses escaped values", func() {
+		annotations := map[string]string{
+			v1.NetworkStatusAnnot:         networkStatus,
+			"kubernetes.io/config.source": "api",
+			"example.com/quoted":          "a \"quoted\"\tvalue ✓",
+		}
+		parsed, err := ParseAnnotations(formatAnnotations(annotations))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed).To(Equal(annotations))
+	})
+
+	It("parses an empty file", func() {
+		parsed, err := ParseAnnotations(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed).To(BeEmpty())
+	})
+
+	It("reports the line of invalid entries", func() {
+		_, err := ParseAnnotations([]byte("a=\"1\"\n\nb=2\n"))
+		Expect(err).To(MatchError(ContainSubstring("line 3: invalid value for b")))
+		_, err = ParseAnnotations([]byte("a=\"1\"\n=\"2\"\n"))
+		Expect(err).To(MatchError(ContainSubstring("line 2: expected key=\"value\"")))
+	})
+
+	It("decodes the network status", func() {
+		statuses, err := NetworkStatus(map[string]string{v1.NetworkStatusAnnot: networkStatus})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses).To(HaveLen(2))
+		Expect(statuses[1].Name).To(Equal("default/net-a"))
+		Expect(statuses[1].Mac).To(Equal("ee:ee:ee:ee:ee:ee"))
+
+		statuses, err = NetworkStatus(map[string]string{v1.LegacyNetworkStatusAnnot: networkStatus})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses).To(HaveLen(2))
+
+		_, err = NetworkStatus(map[string]string{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reads the network status from a file", func() {
+		tmpDir, err := ioutil.TempDir("", "podinfo")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+
+		path := filepath.Join(tmpDir, "annotations")
+		Expect(ioutil.WriteFile(path, formatAnnotations(map[string]string{v1.NetworkStatusAnnot: networkStatus}), 0644)).To(Succeed())
+		statuses, err := ReadNetworkStatus(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses).To(HaveLen(2))
+
+		_, err = ReadNetworkStatus(filepath.Join(tmpDir, "missing"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
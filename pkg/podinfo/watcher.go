@@ -0,0 +1,145 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podinfo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/golang/glog"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+// Watcher sends the network status of the pod each time it changes in a
+// downward API annotations file
+type Watcher struct {
+	path    string
+	updates chan []v1.NetworkStatus
+
+	lock sync.Mutex
+	last []v1.NetworkStatus
+}
+
+// NewWatcher returns a Watcher for the annotations file at path, or at
+// DefaultAnnotationsPath if path is empty. Call Run to start it.
+func NewWatcher(path string) *Watcher {
+	if path == "" {
+		path = DefaultAnnotationsPath
+	}
+	return &Watcher{path: path, updates: make(chan []v1.NetworkStatus)}
+}
+
+// Updates returns the channel the network status is sent on. It is closed
+// when Run returns.
+func (w *Watcher) Updates() <-chan []v1.NetworkStatus {
+	return w.updates
+}
+
+// Get returns the network status last sent, or nil
+func (w *Watcher) Get() []v1.NetworkStatus {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return append([]v1.NetworkStatus(nil), w.last...)
+}
+
+// Run sends the current network status, if there is one, then watches the
+// file until ctx is done. The directory of the file is watched since the
+// kubelet updates downward API volumes by swapping a symlink.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.updates)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	dir := filepath.Dir(w.path)
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %v", dir, err)
+	}
+
+	if !w.sync(ctx) {
+		return nil
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !w.sync(ctx) {
+				return nil
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			glog.Warningf("podinfo: error watching %s: %v", dir, err)
+		}
+	}
+}
+
+// sync reads the network status and sends it if it changed. A missing file
+// or annotation is not reported, the status is only sent once it is set.
+// It returns false if ctx is done.
+func (w *Watcher) sync(ctx context.Context) bool {
+	annotations, err := ReadAnnotations(w.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			glog.Warningf("podinfo: %v", err)
+		}
+		return true
+	}
+	_, ok := annotations[v1.NetworkStatusAnnot]
+	if !ok {
+		_, ok = annotations[v1.LegacyNetworkStatusAnnot]
+	}
+	if !ok {
+		return true
+	}
+	statuses, err := NetworkStatus(annotations)
+	if err != nil {
+		glog.Warningf("podinfo: invalid network status in %s: %v", w.path, err)
+		return true
+	}
+
+	w.lock.Lock()
+	if w.last != nil && reflect.DeepEqual(w.last, statuses) {
+		w.lock.Unlock()
+		return true
+	}
+	if statuses == nil {
+		statuses = []v1.NetworkStatus{}
+	}
+	w.last = statuses
+	w.lock.Unlock()
+
+	select {
+	case w.updates <- append([]v1.NetworkStatus(nil), statuses...):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podinfo
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Network status watcher", func() {
+	var tmpDir string
+	var watcher *Watcher
+	var cancel context.CancelFunc
+	var done chan error
+
+	// writeAnnotations updates the volume like the kubelet: the data is
+	// written to a new directory and the ..data symlink swapped to it
+	writeAnnotations := func(annotations map[string]string) {
+		dataDir := filepath.Join(tmpDir, "..data-"+time.Now().Format("150405.000000000"))
+		Expect(os.Mkdir(dataDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dataDir, "annotations"), formatAnnotations(annotations), 0644)).To(Succeed())
+
+		tmpLink := filepath.Join(tmpDir, "..data_tmp")
+		Expect(os.Symlink(filepath.Base(dataDir), tmpLink)).To(Succeed())
+		Expect(os.Rename(tmpLink, filepath.Join(tmpDir, "..data"))).To(Succeed())
+	}
+
+	nextUpdate := func() []v1.NetworkStatus {
+		var statuses []v1.NetworkStatus
+		Eventually(watcher.Updates(), 5*time.Second).Should(Receive(&statuses))
+		return statuses
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "podinfo")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Symlink(filepath.Join("..data", "annotations"), filepath.Join(tmpDir, "annotations"))).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		watcher = NewWatcher(filepath.Join(tmpDir, "annotations"))
+		done = make(chan error, 1)
+		go func() {
+			done <- watcher.Run(ctx)
+		}()
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done, 5*time.Second).Should(Receive(BeNil()))
+		Expect(watcher.Updates()).To(BeClosed())
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	Context("with an existing network status", func() {
+		BeforeEach(func() {
+			writeAnnotations(map[string]string{v1.NetworkStatusAnnot: networkStatus})
+		})
+
+		It("sends it on start", func() {
+			Expect(nextUpdate()).To(HaveLen(2))
+			Expect(watcher.Get()).To(HaveLen(2))
+			Consistently(watcher.Updates(), 200*time.Millisecond).ShouldNot(Receive())
+		})
+	})
+
+	It("sends the network status once it is set and when it changes", func() {
+		writeAnnotations(map[string]string{"kubernetes.io/config.source": "api"})
+		Consistently(watcher.Updates(), 200*time.Millisecond).ShouldNot(Receive())
+		Expect(watcher.Get()).To(BeNil())
+
+		writeAnnotations(map[string]string{v1.NetworkStatusAnnot: networkStatus})
+		Expect(nextUpdate()).To(HaveLen(2))
+
+		// unrelated annotation changes are not reported
+		writeAnnotations(map[string]string{v1.NetworkStatusAnnot: networkStatus, "example.com/foo": "bar"})
+		Consistently(watcher.Updates(), 200*time.Millisecond).ShouldNot(Receive())
+
+		writeAnnotations(map[string]string{v1.NetworkStatusAnnot: `[{"name": "cbr0", "interface": "eth0", "default": true}]`})
+		statuses := nextUpdate()
+		Expect(statuses).To(Equal([]v1.NetworkStatus{{Name: "cbr0", Interface: "eth0", Default: true}}))
+		Expect(watcher.Get()).To(Equal(statuses))
+	})
+})
@@ -27,15 +27,47 @@ import (
 )
 
 const (
-	baseDevInfoPath  = "/var/run/k8s.cni.cncf.io/devinfo"
+	// DefaultDeviceInfoPath is the root directory of the default DeviceInfoStore
+	DefaultDeviceInfoPath = "/var/run/k8s.cni.cncf.io/devinfo"
+
 	dpDevInfoSubDir  = "dp"
 	cniDevInfoSubDir = "cni"
+	dpDevInfoSuffix  = "-device.json"
+
+	devInfoDirPerm   = 0755
+	devInfoFilePerm  = 0444
+	devInfoTmpPrefix = ".devinfo-"
 )
 
-// GetCNIConfig (from annotation string to CNI JSON bytes)
+// EmptySpecConfigError is returned for a Spec.Config that is empty or only
+// made of whitespace
+type EmptySpecConfigError struct {
+	// Whitespace is true if the config is not empty but only whitespace
+	Whitespace bool
+}
+
+func (e *EmptySpecConfigError) Error() string {
+	if e.Whitespace {
+		return "Spec.Config only contains whitespace"
+	}
+	return "Spec.Config is empty"
+}
+
+// NonObjectSpecConfigError is returned for a Spec.Config that is valid JSON
+// but not an object
+type NonObjectSpecConfigError struct {
+	// Kind is the JSON type found: null, array, string, number or boolean
+	Kind string
+}
+
+func (e *NonObjectSpecConfigError) Error() string {
+	return fmt.Sprintf("Spec.Config must be a JSON object, got %s", e.Kind)
+}
+
+// GetCNIConfig (from annotation string to CNI JSON bytes). A Spec.Config
+// that is empty or only whitespace is read from the configuration directory.
 func GetCNIConfig(net *v1.NetworkAttachmentDefinition, confDir string) (config []byte, err error) {
-	emptySpec := v1.NetworkAttachmentDefinitionSpec{}
-	if net.Spec == emptySpec {
+	if IsEmptySpecConfig(net.Spec.Config) {
 		// Network Spec empty; generate delegate from CNI JSON config
 		// from the configuration directory that has the same network
 		// name as the custom resource
@@ -49,7 +81,7 @@ func GetCNIConfig(net *v1.NetworkAttachmentDefinition, confDir string) (config [
 		// execute.
 		config, err = GetCNIConfigFromSpec(net.Spec.Config, net.Name)
 		if err != nil {
-			return nil, fmt.Errorf("GetCNIConfig: err in getCNIConfigFromSpec: %v", err)
+			return nil, fmt.Errorf("GetCNIConfig: err in getCNIConfigFromSpec: %w", err)
 		}
 	}
 	return config, nil
@@ -103,18 +135,49 @@ func GetCNIConfigFromFile(name, confDir string) ([]byte, error) {
 	return nil, fmt.Errorf("no network available in the name %s in cni dir %s", name, confDir)
 }
 
+// IsEmptySpecConfig returns true if config, the Spec.Config of a
+// NetworkAttachmentDefinition, is empty or only made of whitespace. The CNI
+// configuration is then read from disk, see GetCNIConfig.
+func IsEmptySpecConfig(config string) bool {
+	return strings.TrimSpace(config) == ""
+}
+
 // GetCNIConfigFromSpec reads a CNI JSON configuration from the NetworkAttachmentDefinition
-// object's Spec.Config field and fills in any missing details like the network name
+// object's Spec.Config field and fills in any missing details like the network name.
+// It returns an *EmptySpecConfigError or a *NonObjectSpecConfigError if
+// configData does not hold a JSON object.
 func GetCNIConfigFromSpec(configData, netName string) ([]byte, error) {
-	var rawConfig map[string]interface{}
+	if IsEmptySpecConfig(configData) {
+		return nil, &EmptySpecConfigError{Whitespace: configData != ""}
+	}
+
+	var value interface{}
 	var err error
 
 	configBytes := []byte(configData)
-	err = json.Unmarshal(configBytes, &rawConfig)
+	err = json.Unmarshal(configBytes, &value)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal Spec.Config: %v", err)
 	}
 
+	var rawConfig map[string]interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		rawConfig = v
+	case nil:
+		return nil, &NonObjectSpecConfigError{Kind: "null"}
+	case []interface{}:
+		return nil, &NonObjectSpecConfigError{Kind: "array"}
+	case string:
+		return nil, &NonObjectSpecConfigError{Kind: "string"}
+	case float64:
+		return nil, &NonObjectSpecConfigError{Kind: "number"}
+	case bool:
+		return nil, &NonObjectSpecConfigError{Kind: "boolean"}
+	default:
+		return nil, &NonObjectSpecConfigError{Kind: fmt.Sprintf("%T", v)}
+	}
+
 	// Inject network name if missing from Config for the thick plugin case
 	if n, ok := rawConfig["name"]; !ok || n == "" {
 		rawConfig["name"] = netName
@@ -127,111 +190,274 @@ func GetCNIConfigFromSpec(configData, netName string) ([]byte, error) {
 	return configBytes, nil
 }
 
+// deviceInfoOptions holds the settings of a DeviceInfoStore
+type deviceInfoOptions struct {
+	// validate enforces ValidateDeviceInfo on load and save
+	validate bool
+	// version is the Device Information version written on save
+	version string
+}
+
+// DeviceInfoStoreOption configures a DeviceInfoStore
+type DeviceInfoStoreOption func(*deviceInfoOptions)
+
+// WithDeviceInfoValidation makes the store reject Device Information that
+// does not pass ValidateDeviceInfo, both when loading and when saving
+func WithDeviceInfoValidation() DeviceInfoStoreOption {
+	return func(o *deviceInfoOptions) {
+		o.validate = true
+	}
+}
+
+// WithDeviceInfoVersion makes the store write Device Information in the
+// given version, for compatibility with older consumers. By default the
+// version set by the caller is written.
+func WithDeviceInfoVersion(version string) DeviceInfoStoreOption {
+	return func(o *deviceInfoOptions) {
+		o.version = version
+	}
+}
+
+func newDeviceInfoOptions(opts []DeviceInfoStoreOption) deviceInfoOptions {
+	var o deviceInfoOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// check validates devInfo if the options ask for it
+func (o deviceInfoOptions) check(devInfo *v1.DeviceInfo) error {
+	if !o.validate {
+		return nil
+	}
+	if err := ValidateDeviceInfo(devInfo); err != nil {
+		return fmt.Errorf("invalid Device Information: %v", err)
+	}
+	return nil
+}
+
+// encode encodes devInfo in the configured version, validating what is
+// written if the options ask for it
+func (o deviceInfoOptions) encode(devInfo *v1.DeviceInfo) ([]byte, error) {
+	written, err := deviceInfoInVersion(devInfo, o.version)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.check(written); err != nil {
+		return nil, err
+	}
+	return json.Marshal(written)
+}
+
+// decode decodes Device Information, validating it if the options ask for
+// it, and upgrades it to DeviceInfoVersion
+func (o deviceInfoOptions) decode(data []byte) (*v1.DeviceInfo, error) {
+	devInfo, err := o.decodeAsWritten(data)
+	if err != nil {
+		return nil, err
+	}
+	devInfo.Version = v1.DeviceInfoVersion
+	return devInfo, nil
+}
+
+// decodeAsWritten decodes Device Information, validating it if the options
+// ask for it, keeping the version it was written in so that it can be
+// written again in the same version
+func (o deviceInfoOptions) decodeAsWritten(data []byte) (*v1.DeviceInfo, error) {
+	devInfo, err := decodeDeviceInfoAsWritten(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.check(devInfo); err != nil {
+		return nil, err
+	}
+	return devInfo, nil
+}
+
 // loadDeviceInfo loads a Device Information file
-func loadDeviceInfo(path string) (*v1.DeviceInfo, error) {
-	var devInfo v1.DeviceInfo
+func loadDeviceInfo(path string, opts deviceInfoOptions) (*v1.DeviceInfo, error) {
+	return loadDeviceInfoWith(path, opts.decode)
+}
 
+// loadDeviceInfoWith loads a Device Information file with decode
+func loadDeviceInfoWith(path string, decode func([]byte) (*v1.DeviceInfo, error)) (*v1.DeviceInfo, error) {
 	bytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	err = json.Unmarshal(bytes, &devInfo)
+	devInfo, err := decode(bytes)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%s: %w", path, err)
 	}
 
-	return &devInfo, nil
+	return devInfo, nil
 }
 
 // cleanDeviceInfo removes a Device Information file
 func cleanDeviceInfo(path string) error {
-	if _, err := os.Stat(path); !os.IsNotExist(err) {
-		return os.Remove(path)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
 	}
 	return nil
 }
 
-// saveDeviceInfo writes a Device Information file
-func saveDeviceInfo(devInfo *v1.DeviceInfo, path string) error {
-	if devInfo == nil {
-		return fmt.Errorf("Device Information is null")
+// saveDeviceInfo writes a Device Information file. The content is written
+// to a temporary file in the same directory, synced to disk and then linked
+// into place, so readers never observe a partially written file and only one
+// of several concurrent writers succeeds.
+func saveDeviceInfo(devInfo *v1.DeviceInfo, path string, opts deviceInfoOptions) error {
+	devInfoJSON, err := opts.encode(devInfo)
+	if err != nil {
+		return err
 	}
 
 	dir := filepath.Dir(path)
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err := os.MkdirAll(dir, os.ModeDir); err != nil {
-			return err
-		}
+	if err := os.MkdirAll(dir, devInfoDirPerm); err != nil {
+		return err
 	}
 
-	if _, err := os.Stat(path); !os.IsNotExist(err) {
-		return fmt.Errorf("Device Information file already exists: %s", path)
+	tmpFile, err := ioutil.TempFile(dir, devInfoTmpPrefix)
+	if err != nil {
+		return err
 	}
+	tmpPath := tmpFile.Name()
+	// The temporary name is always removed: on success the data stays
+	// reachable through path.
+	defer os.Remove(tmpPath)
 
-	devInfoJSON, err := json.Marshal(devInfo)
-	if err != nil {
+	if err := writeAndSync(tmpFile, devInfoJSON); err != nil {
 		return err
 	}
 
-	if err := ioutil.WriteFile(path, devInfoJSON, 0444); err != nil {
+	// link(2) fails with EEXIST instead of replacing an existing file,
+	// which gives O_EXCL semantics to the atomic publish.
+	if err := os.Link(tmpPath, path); err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("Device Information file already exists: %s", path)
+		}
 		return err
 	}
-	return nil
+
+	return syncDir(dir)
+}
+
+// writeAndSync writes data to f, flushes it to stable storage and closes f
+func writeAndSync(f *os.File, data []byte) error {
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(devInfoFilePerm); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir flushes directory entries of dir to stable storage
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
 }
 
 // getDPDeviceInfoPath returns the standard Device Plugin DevInfo filename
 // This filename is fixed because Device Plugin and NPWG Implementation need
 // to both access file and name is not passed between them. So name is generated
 // from Resource Name and DeviceID.
-func getDPDeviceInfoPath(resourceName string, deviceID string) string {
-	return filepath.Join(baseDevInfoPath, dpDevInfoSubDir, fmt.Sprintf("%s-%s-device.json",
-		strings.ReplaceAll(resourceName, "/", "-"), strings.ReplaceAll(deviceID, "/", "-")))
+func getDPDeviceInfoPath(root, resourceName, deviceID string) string {
+	return filepath.Join(root, dpDevInfoSubDir, fmt.Sprintf("%s-%s%s",
+		strings.ReplaceAll(resourceName, "/", "-"), strings.ReplaceAll(deviceID, "/", "-"), dpDevInfoSuffix))
+}
+
+// ParseDPDeviceInfoFilename parses the resource name and device ID back from
+// a Device Plugin DevInfo filename as generated by getDPDeviceInfoPath.
+// Slashes are replaced by dashes in the filename, so the resource name is
+// only recovered as-is if it is one of resourceNames. Otherwise the device ID
+// is taken to be the part after the last dash and the resource name is
+// returned in its sanitized form.
+func ParseDPDeviceInfoFilename(filename string, resourceNames ...string) (resourceName, deviceID string, err error) {
+	base := filepath.Base(filename)
+	name := strings.TrimSuffix(base, dpDevInfoSuffix)
+	if name == base || name == "" {
+		return "", "", fmt.Errorf("%q is not a Device Plugin Device Information file", base)
+	}
+
+	for _, resourceName := range resourceNames {
+		prefix := strings.ReplaceAll(resourceName, "/", "-") + "-"
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return resourceName, name[len(prefix):], nil
+		}
+	}
+
+	i := strings.LastIndex(name, "-")
+	if i <= 0 || i == len(name)-1 {
+		return "", "", fmt.Errorf("%q is not a Device Plugin Device Information file", base)
+	}
+	return name[:i], name[i+1:], nil
+}
+
+// getCNIDeviceInfoPath returns the standard CNI DevInfo filename below root
+func getCNIDeviceInfoPath(root, filename string) string {
+	return filepath.Join(root, cniDevInfoSubDir, strings.ReplaceAll(filename, "/", "-"))
 }
 
 // GetCNIDeviceInfoPath returns the standard Device Plugin DevInfo filename
 // The path is fixed but the filename is flexible and determined by the caller.
 func GetCNIDeviceInfoPath(filename string) string {
-	return filepath.Join(baseDevInfoPath, cniDevInfoSubDir, strings.ReplaceAll(filename, "/", "-"))
+	return defaultDeviceInfoStore.CNIDeviceInfoPath(filename)
 }
 
 // LoadDeviceInfoFromDP loads a DeviceInfo structure from file created by a Device Plugin
 // Returns an error if the device information is malformed and (nil, nil) if it does not exist
 func LoadDeviceInfoFromDP(resourceName string, deviceID string) (*v1.DeviceInfo, error) {
-	return loadDeviceInfo(getDPDeviceInfoPath(resourceName, deviceID))
+	store := defaultDeviceInfoStore
+	return store.Load(store.DPDeviceInfoPath(resourceName, deviceID))
 }
 
 // SaveDeviceInfoForDP saves a DeviceInfo structure created by a Device Plugin
 func SaveDeviceInfoForDP(resourceName string, deviceID string, devInfo *v1.DeviceInfo) error {
-	return saveDeviceInfo(devInfo, getDPDeviceInfoPath(resourceName, deviceID))
+	store := defaultDeviceInfoStore
+	return store.Save(store.DPDeviceInfoPath(resourceName, deviceID), devInfo)
 }
 
 // CleanDeviceInfoForDP removes a DeviceInfo DP File.
 func CleanDeviceInfoForDP(resourceName string, deviceID string) error {
-	return cleanDeviceInfo(getDPDeviceInfoPath(resourceName, deviceID))
+	store := defaultDeviceInfoStore
+	return store.Clean(store.DPDeviceInfoPath(resourceName, deviceID))
 }
 
 // LoadDeviceInfoFromCNI loads a DeviceInfo structure from created by a CNI.
 // Returns an error if the device information is malformed and (nil, nil) if it does not exist
 func LoadDeviceInfoFromCNI(cniPath string) (*v1.DeviceInfo, error) {
-	return loadDeviceInfo(cniPath)
+	return defaultDeviceInfoStore.Load(cniPath)
 }
 
 // SaveDeviceInfoForCNI saves a DeviceInfo structure created by a CNI
 func SaveDeviceInfoForCNI(cniPath string, devInfo *v1.DeviceInfo) error {
-	return saveDeviceInfo(devInfo, cniPath)
+	return defaultDeviceInfoStore.Save(cniPath, devInfo)
 }
 
 // CopyDeviceInfoForCNIFromDP saves a DeviceInfo structure created by a DP to a CNI File.
 func CopyDeviceInfoForCNIFromDP(cniPath string, resourceName string, deviceID string) error {
-	devInfo, err := loadDeviceInfo(getDPDeviceInfoPath(resourceName, deviceID))
+	store := defaultDeviceInfoStore
+	devInfo, err := loadDeviceInfoAsWritten(store, store.DPDeviceInfoPath(resourceName, deviceID))
 	if err != nil {
 		return err
 	}
-	return saveDeviceInfo(devInfo, cniPath)
+	return store.Save(cniPath, devInfo)
 }
 
 // CleanDeviceInfoForCNI removes a DeviceInfo CNI File.
 func CleanDeviceInfoForCNI(cniPath string) error {
-	return cleanDeviceInfo(cniPath)
+	return defaultDeviceInfoStore.Clean(cniPath)
 }
@@ -15,13 +15,16 @@
 package utils
 
 import (
+	"errors"
 	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	"io/ioutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"os"
 	"path/filepath"
+	"sync"
 
 	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -104,6 +107,29 @@ var _ = Describe("CNI config manipulations", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(config).To(Equal([]byte(cniConfig)))
 		})
+
+		It("test net-attach-def with whitespace-only config and conf file", func() {
+			tmpConfFilePath := filepath.Join(tmpDir, "testCNI.conf")
+			cniConfig := `{
+			"type": "test",
+			"name": "test-net-attach-def",
+			"version": "0.3.1"
+		}`
+			ioutil.WriteFile(tmpConfFilePath, []byte(cniConfig), 0644)
+
+			netattachdef := v1.NetworkAttachmentDefinition{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-net-attach-def",
+					Namespace: "testnamespace",
+				},
+				Spec: v1.NetworkAttachmentDefinitionSpec{
+					Config: " \n\t ",
+				},
+			}
+			config, err := GetCNIConfig(&netattachdef, tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).To(Equal([]byte(cniConfig)))
+		})
 	})
 
 	Context("Invalid case", func() {
@@ -122,6 +148,43 @@ var _ = Describe("CNI config manipulations", func() {
 			Expect(err).To(HaveOccurred())
 		})
 
+		It("test net-attach-def with null config", func() {
+			netattachdef := v1.NetworkAttachmentDefinition{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-net-attach-def",
+					Namespace: "testnamespace",
+				},
+				Spec: v1.NetworkAttachmentDefinitionSpec{
+					Config: "null",
+				},
+			}
+			_, err := GetCNIConfig(&netattachdef, "")
+			var nonObjectErr *NonObjectSpecConfigError
+			Expect(errors.As(err, &nonObjectErr)).To(BeTrue())
+			Expect(nonObjectErr.Kind).To(Equal("null"))
+		})
+
+		table.DescribeTable("test net-attach-def with non-object config",
+			func(config, kind string) {
+				_, err := GetCNIConfigFromSpec(config, "test-net-attach-def")
+				Expect(err).To(Equal(&NonObjectSpecConfigError{Kind: kind}))
+				Expect(err).To(MatchError("Spec.Config must be a JSON object, got " + kind))
+			},
+			table.Entry("null", " null ", "null"),
+			table.Entry("an array", `[{"type": "test"}]`, "array"),
+			table.Entry("a string", `"config"`, "string"),
+			table.Entry("a number", "42", "number"),
+			table.Entry("a boolean", "true", "boolean"),
+		)
+
+		It("test empty and whitespace-only config", func() {
+			_, err := GetCNIConfigFromSpec("", "test-net-attach-def")
+			Expect(err).To(Equal(&EmptySpecConfigError{}))
+			_, err = GetCNIConfigFromSpec(" \n\t", "test-net-attach-def")
+			Expect(err).To(Equal(&EmptySpecConfigError{Whitespace: true}))
+			Expect(err).To(MatchError("Spec.Config only contains whitespace"))
+		})
+
 		It("test net-attach-def with invalid conf file", func() {
 			tmpConfFilePath := filepath.Join(tmpDir, "testCNI.conf")
 			cniConfig := `***invalid json file***`
@@ -153,4 +216,111 @@ var _ = Describe("CNI config manipulations", func() {
 		})
 	})
 
+	Context("Device Information files", func() {
+		var devInfo *v1.DeviceInfo
+		var devInfoPath string
+
+		BeforeEach(func() {
+			devInfo = &v1.DeviceInfo{
+				Type:    v1.DeviceInfoTypePCI,
+				Version: v1.DeviceInfoVersion,
+				Pci: &v1.PciDevice{
+					PciAddress: "0000:01:02.2",
+				},
+			}
+			devInfoPath = filepath.Join(tmpDir, "devinfo", "dp", "resource-device.json")
+		})
+
+		It("saves and loads device information", func() {
+			Expect(saveDeviceInfo(devInfo, devInfoPath, deviceInfoOptions{})).To(Succeed())
+
+			loaded, err := loadDeviceInfo(devInfoPath, deviceInfoOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).To(Equal(devInfo))
+
+			dirInfo, err := os.Stat(filepath.Dir(devInfoPath))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dirInfo.Mode().Perm() & 0700).To(Equal(os.FileMode(0700)))
+			fileInfo, err := os.Stat(devInfoPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fileInfo.Mode().Perm()).To(Equal(os.FileMode(0444)))
+		})
+
+		It("refuses to overwrite existing device information", func() {
+			Expect(saveDeviceInfo(devInfo, devInfoPath, deviceInfoOptions{})).To(Succeed())
+			err := saveDeviceInfo(&v1.DeviceInfo{Type: v1.DeviceInfoTypeVDPA}, devInfoPath, deviceInfoOptions{})
+			Expect(err).To(MatchError(ContainSubstring("already exists")))
+
+			loaded, err := loadDeviceInfo(devInfoPath, deviceInfoOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).To(Equal(devInfo))
+		})
+
+		It("leaves no temporary files behind", func() {
+			Expect(saveDeviceInfo(devInfo, devInfoPath, deviceInfoOptions{})).To(Succeed())
+			Expect(saveDeviceInfo(devInfo, devInfoPath, deviceInfoOptions{})).NotTo(Succeed())
+
+			entries, err := ioutil.ReadDir(filepath.Dir(devInfoPath))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name()).To(Equal(filepath.Base(devInfoPath)))
+		})
+
+		It("lets exactly one of several concurrent writers succeed", func() {
+			const writers = 16
+			var wg sync.WaitGroup
+			errs := make(chan error, writers)
+			for i := 0; i < writers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer GinkgoRecover()
+					errs <- saveDeviceInfo(devInfo, devInfoPath, deviceInfoOptions{})
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			succeeded := 0
+			for err := range errs {
+				if err == nil {
+					succeeded++
+				} else {
+					Expect(err).To(MatchError(ContainSubstring("already exists")))
+				}
+			}
+			Expect(succeeded).To(Equal(1))
+
+			loaded, err := loadDeviceInfo(devInfoPath, deviceInfoOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).To(Equal(devInfo))
+		})
+
+		It("cleans device information idempotently", func() {
+			Expect(saveDeviceInfo(devInfo, devInfoPath, deviceInfoOptions{})).To(Succeed())
+			Expect(cleanDeviceInfo(devInfoPath)).To(Succeed())
+			Expect(cleanDeviceInfo(devInfoPath)).To(Succeed())
+			_, err := os.Stat(devInfoPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("parses device plugin device information filenames", func() {
+			path := getDPDeviceInfoPath(tmpDir, "example.com/sriov-net", "0000:01:02.2")
+
+			resourceName, deviceID, err := ParseDPDeviceInfoFilename(path, "example.com/other", "example.com/sriov-net")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resourceName).To(Equal("example.com/sriov-net"))
+			Expect(deviceID).To(Equal("0000:01:02.2"))
+
+			resourceName, deviceID, err = ParseDPDeviceInfoFilename(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resourceName).To(Equal("example.com-sriov-net"))
+			Expect(deviceID).To(Equal("0000:01:02.2"))
+
+			for _, name := range []string{".devinfo-123456", "net1-container", "-device.json", "resource-device.json"} {
+				_, _, err = ParseDPDeviceInfoFilename(name)
+				Expect(err).To(HaveOccurred(), name)
+			}
+		})
+	})
 })
@@ -0,0 +1,283 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+// DeviceInfoStore persists the DeviceInfo exchanged between Device Plugins,
+// CNIs and NPWG implementations. Entries are addressed by path; the path
+// helpers return the standard locations for Device Plugin and CNI entries.
+type DeviceInfoStore interface {
+	// DPDeviceInfoPath returns the path of the DeviceInfo saved by a
+	// Device Plugin for the given resource and device
+	DPDeviceInfoPath(resourceName, deviceID string) string
+	// CNIDeviceInfoPath returns the path of a DeviceInfo saved by a CNI
+	CNIDeviceInfoPath(filename string) string
+	// Load returns the DeviceInfo saved at path
+	Load(path string) (*v1.DeviceInfo, error)
+	// Save stores devInfo at path, failing if path already holds one
+	Save(path string, devInfo *v1.DeviceInfo) error
+	// Clean removes the DeviceInfo at path, if any
+	Clean(path string) error
+	// List returns all Device Plugin and CNI entries of the store
+	List() ([]DeviceInfoEntry, error)
+}
+
+// asWrittenLoader is implemented by the stores of this package, which can
+// load DeviceInfo in the version it was written in
+type asWrittenLoader interface {
+	loadAsWritten(path string) (*v1.DeviceInfo, error)
+}
+
+// loadDeviceInfoAsWritten loads the DeviceInfo at path in the version it was
+// written in if store supports it, upgraded by Load otherwise
+func loadDeviceInfoAsWritten(store DeviceInfoStore, path string) (*v1.DeviceInfo, error) {
+	if loader, ok := store.(asWrittenLoader); ok {
+		return loader.loadAsWritten(path)
+	}
+	return store.Load(path)
+}
+
+// DeviceInfoOwner tells which side of the exchange saved a DeviceInfo
+type DeviceInfoOwner string
+
+const (
+	// DeviceInfoOwnerDP marks DeviceInfo saved by a Device Plugin
+	DeviceInfoOwnerDP DeviceInfoOwner = dpDevInfoSubDir
+	// DeviceInfoOwnerCNI marks DeviceInfo saved by a CNI
+	DeviceInfoOwnerCNI DeviceInfoOwner = cniDevInfoSubDir
+)
+
+// DeviceInfoEntry describes one DeviceInfo held by a DeviceInfoStore
+type DeviceInfoEntry struct {
+	Path    string
+	Owner   DeviceInfoOwner
+	ModTime time.Time
+}
+
+// defaultDeviceInfoStore backs the package level DeviceInfo helpers
+var defaultDeviceInfoStore DeviceInfoStore = NewFSDeviceInfoStore(DefaultDeviceInfoPath)
+
+// DefaultDeviceInfoStore returns the store used by the package level DeviceInfo helpers
+func DefaultDeviceInfoStore() DeviceInfoStore {
+	return defaultDeviceInfoStore
+}
+
+// SetDefaultDeviceInfoStore replaces the store used by the package level
+// DeviceInfo helpers. It is not safe to call concurrently with those helpers
+// and is meant to be called once during initialization.
+func SetDefaultDeviceInfoStore(store DeviceInfoStore) {
+	if store == nil {
+		store = NewFSDeviceInfoStore(DefaultDeviceInfoPath)
+	}
+	defaultDeviceInfoStore = store
+}
+
+// FSDeviceInfoStore keeps DeviceInfo as JSON files below a root directory
+type FSDeviceInfoStore struct {
+	root string
+	opts deviceInfoOptions
+}
+
+// NewFSDeviceInfoStore returns a DeviceInfoStore rooted at root.
+// An empty root selects DefaultDeviceInfoPath.
+func NewFSDeviceInfoStore(root string, opts ...DeviceInfoStoreOption) *FSDeviceInfoStore {
+	if root == "" {
+		root = DefaultDeviceInfoPath
+	}
+	return &FSDeviceInfoStore{root: root, opts: newDeviceInfoOptions(opts)}
+}
+
+// Root returns the root directory of the store
+func (s *FSDeviceInfoStore) Root() string {
+	return s.root
+}
+
+// DPDeviceInfoPath returns the path of the DeviceInfo file saved by a Device Plugin
+func (s *FSDeviceInfoStore) DPDeviceInfoPath(resourceName, deviceID string) string {
+	return getDPDeviceInfoPath(s.root, resourceName, deviceID)
+}
+
+// CNIDeviceInfoPath returns the path of a DeviceInfo file saved by a CNI
+func (s *FSDeviceInfoStore) CNIDeviceInfoPath(filename string) string {
+	return getCNIDeviceInfoPath(s.root, filename)
+}
+
+// Load reads the DeviceInfo file at path
+func (s *FSDeviceInfoStore) Load(path string) (*v1.DeviceInfo, error) {
+	return loadDeviceInfo(path, s.opts)
+}
+
+func (s *FSDeviceInfoStore) loadAsWritten(path string) (*v1.DeviceInfo, error) {
+	return loadDeviceInfoWith(path, s.opts.decodeAsWritten)
+}
+
+// Save writes the DeviceInfo file at path
+func (s *FSDeviceInfoStore) Save(path string, devInfo *v1.DeviceInfo) error {
+	return saveDeviceInfo(devInfo, path, s.opts)
+}
+
+// Clean removes the DeviceInfo file at path
+func (s *FSDeviceInfoStore) Clean(path string) error {
+	return cleanDeviceInfo(path)
+}
+
+// List returns the DeviceInfo files of the dp and cni subdirectories,
+// sorted by path. Temporary files of in-flight writes are skipped.
+func (s *FSDeviceInfoStore) List() ([]DeviceInfoEntry, error) {
+	var entries []DeviceInfoEntry
+	for _, owner := range []DeviceInfoOwner{DeviceInfoOwnerDP, DeviceInfoOwnerCNI} {
+		dir := filepath.Join(s.root, string(owner))
+		files, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, f := range files {
+			if f.IsDir() || strings.HasPrefix(f.Name(), devInfoTmpPrefix) {
+				continue
+			}
+			entries = append(entries, DeviceInfoEntry{
+				Path:    filepath.Join(dir, f.Name()),
+				Owner:   owner,
+				ModTime: f.ModTime(),
+			})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+// MemDeviceInfoStore keeps DeviceInfo in memory. It uses the same path
+// scheme as an FSDeviceInfoStore but never touches the filesystem, which
+// makes it suitable for unit tests.
+type MemDeviceInfoStore struct {
+	root string
+	opts deviceInfoOptions
+
+	lock     sync.Mutex
+	devInfos map[string][]byte
+	modTimes map[string]time.Time
+}
+
+// NewMemDeviceInfoStore returns an empty in-memory DeviceInfoStore
+// whose paths are rooted at DefaultDeviceInfoPath
+func NewMemDeviceInfoStore(opts ...DeviceInfoStoreOption) *MemDeviceInfoStore {
+	return &MemDeviceInfoStore{
+		root:     DefaultDeviceInfoPath,
+		opts:     newDeviceInfoOptions(opts),
+		devInfos: make(map[string][]byte),
+		modTimes: make(map[string]time.Time),
+	}
+}
+
+// DPDeviceInfoPath returns the path of the DeviceInfo saved by a Device Plugin
+func (s *MemDeviceInfoStore) DPDeviceInfoPath(resourceName, deviceID string) string {
+	return getDPDeviceInfoPath(s.root, resourceName, deviceID)
+}
+
+// CNIDeviceInfoPath returns the path of a DeviceInfo saved by a CNI
+func (s *MemDeviceInfoStore) CNIDeviceInfoPath(filename string) string {
+	return getCNIDeviceInfoPath(s.root, filename)
+}
+
+// Load decodes the DeviceInfo saved at path. The returned error satisfies
+// os.IsNotExist when there is none.
+func (s *MemDeviceInfoStore) Load(path string) (*v1.DeviceInfo, error) {
+	return s.load(path, s.opts.decode)
+}
+
+func (s *MemDeviceInfoStore) loadAsWritten(path string) (*v1.DeviceInfo, error) {
+	return s.load(path, s.opts.decodeAsWritten)
+}
+
+func (s *MemDeviceInfoStore) load(path string, decode func([]byte) (*v1.DeviceInfo, error)) (*v1.DeviceInfo, error) {
+	s.lock.Lock()
+	data, ok := s.devInfos[path]
+	s.lock.Unlock()
+
+	if !ok {
+		return nil, &os.PathError{Op: "load", Path: path, Err: os.ErrNotExist}
+	}
+	devInfo, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return devInfo, nil
+}
+
+// Save stores the encoded devInfo at path
+func (s *MemDeviceInfoStore) Save(path string, devInfo *v1.DeviceInfo) error {
+	data, err := s.opts.encode(devInfo)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.devInfos[path]; ok {
+		return fmt.Errorf("Device Information file already exists: %s", path)
+	}
+	s.devInfos[path] = data
+	s.modTimes[path] = time.Now()
+	return nil
+}
+
+// Clean removes the DeviceInfo saved at path
+func (s *MemDeviceInfoStore) Clean(path string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.devInfos, path)
+	delete(s.modTimes, path)
+	return nil
+}
+
+// List returns the DeviceInfo saved below the dp and cni paths of the store,
+// sorted by path
+func (s *MemDeviceInfoStore) List() ([]DeviceInfoEntry, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var entries []DeviceInfoEntry
+	for path := range s.devInfos {
+		owner := DeviceInfoOwner(filepath.Base(filepath.Dir(path)))
+		if filepath.Dir(filepath.Dir(path)) != s.root ||
+			(owner != DeviceInfoOwnerDP && owner != DeviceInfoOwnerCNI) {
+			continue
+		}
+		entries = append(entries, DeviceInfoEntry{
+			Path:    path,
+			Owner:   owner,
+			ModTime: s.modTimes[path],
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Device Information stores", func() {
+	var tmpDir string
+	var devInfo *v1.DeviceInfo
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "devinfo")
+		Expect(err).NotTo(HaveOccurred())
+
+		devInfo = &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypePCI,
+			Version: v1.DeviceInfoVersion,
+			Pci: &v1.PciDevice{
+				PciAddress: "0000:01:02.2",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		SetDefaultDeviceInfoStore(nil)
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	stores := map[string]func() DeviceInfoStore{
+		"filesystem": func() DeviceInfoStore { return NewFSDeviceInfoStore(tmpDir) },
+		"in-memory":  func() DeviceInfoStore { return NewMemDeviceInfoStore() },
+	}
+
+	for name, newStore := range stores {
+		newStore := newStore
+
+		Context("with the "+name+" store", func() {
+			var store DeviceInfoStore
+
+			BeforeEach(func() {
+				store = newStore()
+				SetDefaultDeviceInfoStore(store)
+			})
+
+			It("round-trips device plugin device information", func() {
+				Expect(SaveDeviceInfoForDP("example.com/sriov", "0000:01:02.2", devInfo)).To(Succeed())
+
+				loaded, err := LoadDeviceInfoFromDP("example.com/sriov", "0000:01:02.2")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(loaded).To(Equal(devInfo))
+
+				Expect(CleanDeviceInfoForDP("example.com/sriov", "0000:01:02.2")).To(Succeed())
+				_, err = LoadDeviceInfoFromDP("example.com/sriov", "0000:01:02.2")
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+
+			It("copies device plugin device information for the CNI", func() {
+				cniPath := GetCNIDeviceInfoPath("net1-container")
+				Expect(SaveDeviceInfoForDP("example.com/sriov", "0000:01:02.2", devInfo)).To(Succeed())
+				Expect(CopyDeviceInfoForCNIFromDP(cniPath, "example.com/sriov", "0000:01:02.2")).To(Succeed())
+
+				loaded, err := LoadDeviceInfoFromCNI(cniPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(loaded).To(Equal(devInfo))
+				Expect(SaveDeviceInfoForCNI(cniPath, devInfo)).NotTo(Succeed())
+
+				Expect(CleanDeviceInfoForCNI(cniPath)).To(Succeed())
+				Expect(CleanDeviceInfoForCNI(cniPath)).To(Succeed())
+			})
+
+			It("keeps the device plugin version when copying for the CNI", func() {
+				cniPath := GetCNIDeviceInfoPath("net1-container")
+				devInfo.Version = v1.DeviceInfoVersion100
+				Expect(SaveDeviceInfoForDP("example.com/sriov", "0000:01:02.2", devInfo)).To(Succeed())
+				Expect(CopyDeviceInfoForCNIFromDP(cniPath, "example.com/sriov", "0000:01:02.2")).To(Succeed())
+
+				written, err := loadDeviceInfoAsWritten(store, cniPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(written).To(Equal(devInfo))
+			})
+
+			It("round-trips the device information types of every version", func() {
+				for i, devInfo := range []*v1.DeviceInfo{
+					{
+						Type:    v1.DeviceInfoTypePCI,
+						Version: v1.DeviceInfoVersion100,
+						Pci:     &v1.PciDevice{PciAddress: "0000:01:02.2"},
+					},
+					{
+						Type:    v1.DeviceInfoTypeAuxiliary,
+						Version: v1.DeviceInfoVersion110,
+						Auxiliary: &v1.AuxiliaryDevice{
+							DeviceName:       "mlx5_core.sf.4",
+							ParentPciAddress: "0000:03:00.0",
+							Representor:      "pf0sf4",
+						},
+					},
+					{
+						Type:    v1.DeviceInfoTypeAFXDP,
+						Version: v1.DeviceInfoVersion110,
+						AFXDP:   &v1.AFXDPDevice{Netdev: "ens801f0", Path: "/tmp/afxdp.sock"},
+					},
+				} {
+					path := store.CNIDeviceInfoPath(fmt.Sprintf("devinfo-%d", i))
+					Expect(store.Save(path, devInfo)).To(Succeed())
+					loaded, err := store.Load(path)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(loaded.Version).To(Equal(v1.DeviceInfoVersion))
+					loaded.Version = devInfo.Version
+					Expect(loaded).To(Equal(devInfo))
+				}
+			})
+
+			It("keeps its own copy of the device information", func() {
+				path := store.CNIDeviceInfoPath("copy")
+				Expect(store.Save(path, devInfo)).To(Succeed())
+				devInfo.Pci.PciAddress = "0000:ff:00.0"
+
+				loaded, err := store.Load(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(loaded.Pci.PciAddress).To(Equal("0000:01:02.2"))
+			})
+
+			It("lists device plugin and CNI entries", func() {
+				dpPath := store.DPDeviceInfoPath("example.com/sriov", "0000:01:02.2")
+				cniPath := store.CNIDeviceInfoPath("net1-container")
+				Expect(store.Save(dpPath, devInfo)).To(Succeed())
+				Expect(store.Save(cniPath, devInfo)).To(Succeed())
+
+				entries, err := store.List()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(2))
+				Expect(entries[0].Path).To(Equal(cniPath))
+				Expect(entries[0].Owner).To(Equal(DeviceInfoOwnerCNI))
+				Expect(entries[1].Path).To(Equal(dpPath))
+				Expect(entries[1].Owner).To(Equal(DeviceInfoOwnerDP))
+				Expect(entries[1].ModTime).NotTo(BeZero())
+			})
+		})
+	}
+
+	It("places filesystem entries below the configured root", func() {
+		store := NewFSDeviceInfoStore(tmpDir)
+		Expect(store.DPDeviceInfoPath("example.com/sriov", "0000:01:02.2")).To(Equal(
+			filepath.Join(tmpDir, "dp", "example.com-sriov-0000:01:02.2-device.json")))
+		Expect(store.CNIDeviceInfoPath("pod/net1")).To(Equal(filepath.Join(tmpDir, "cni", "pod-net1")))
+	})
+
+	It("defaults to the standard device information path", func() {
+		Expect(NewFSDeviceInfoStore("").Root()).To(Equal(DefaultDeviceInfoPath))
+		Expect(GetCNIDeviceInfoPath("net1")).To(Equal(filepath.Join(DefaultDeviceInfoPath, "cni", "net1")))
+	})
+})
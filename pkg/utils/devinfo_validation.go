@@ -0,0 +1,251 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+// pciAddressRegexp matches PCI addresses in domain:bus:device.function (BDF) notation
+var pciAddressRegexp = regexp.MustCompile(`^[[:xdigit:]]{4,8}:[[:xdigit:]]{2}:[01][[:xdigit:]]\.[0-7]$`)
+
+var (
+	supportedDeviceInfoTypes = []string{
+		v1.DeviceInfoTypePCI,
+		v1.DeviceInfoTypeVHostUser,
+		v1.DeviceInfoTypeMemif,
+		v1.DeviceInfoTypeVDPA,
+		v1.DeviceInfoTypeAuxiliary,
+		v1.DeviceInfoTypeAFXDP,
+	}
+	supportedDeviceInfoVersions = []string{v1.DeviceInfoVersion100, v1.DeviceInfoVersion110}
+	supportedVdpaDrivers        = []string{"vhost", "virtio"}
+	supportedVhostModes         = []string{v1.VhostDeviceModeClient, v1.VhostDeviceModeServer}
+	supportedMemifRoles         = []string{v1.MemifDeviceRoleMaster, v1.MemitDeviceRoleSlave}
+	supportedMemifModes         = []string{v1.MemifDeviceModeEthernet, v1.MemitDeviceModeIP, v1.MemitDeviceModePunt}
+)
+
+// ValidateDeviceInfo checks that devInfo is consistent: the type is known,
+// the version is supported and introduces the type, exactly the block
+// matching the type is set and its addresses, paths and enumerated modes
+// are well formed.
+// The returned error aggregates all problems found.
+func ValidateDeviceInfo(devInfo *v1.DeviceInfo) error {
+	return validateDeviceInfo(devInfo, field.NewPath("device-info")).ToAggregate()
+}
+
+func validateDeviceInfo(devInfo *v1.DeviceInfo, fldPath *field.Path) field.ErrorList {
+	if devInfo == nil {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+
+	allErrs := validateDeviceInfoVersion(devInfo.Type, devInfo.Version, fldPath.Child("version"))
+
+	blocks := []struct {
+		deviceType string
+		set        bool
+	}{
+		{v1.DeviceInfoTypePCI, devInfo.Pci != nil},
+		{v1.DeviceInfoTypeVDPA, devInfo.Vdpa != nil},
+		{v1.DeviceInfoTypeVHostUser, devInfo.VhostUser != nil},
+		{v1.DeviceInfoTypeMemif, devInfo.Memif != nil},
+		{v1.DeviceInfoTypeAuxiliary, devInfo.Auxiliary != nil},
+		{v1.DeviceInfoTypeAFXDP, devInfo.AFXDP != nil},
+	}
+	for _, block := range blocks {
+		if block.set && block.deviceType != devInfo.Type {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child(block.deviceType),
+				"may only be set for type "+block.deviceType))
+		}
+	}
+
+	switch devInfo.Type {
+	case "":
+		allErrs = append(allErrs, field.Required(fldPath.Child("type"), ""))
+	case v1.DeviceInfoTypePCI:
+		allErrs = append(allErrs, validatePciDevice(devInfo.Pci, fldPath.Child(v1.DeviceInfoTypePCI))...)
+	case v1.DeviceInfoTypeVDPA:
+		allErrs = append(allErrs, validateVdpaDevice(devInfo.Vdpa, fldPath.Child(v1.DeviceInfoTypeVDPA))...)
+	case v1.DeviceInfoTypeVHostUser:
+		allErrs = append(allErrs, validateVhostDevice(devInfo.VhostUser, fldPath.Child(v1.DeviceInfoTypeVHostUser))...)
+	case v1.DeviceInfoTypeMemif:
+		allErrs = append(allErrs, validateMemifDevice(devInfo.Memif, fldPath.Child(v1.DeviceInfoTypeMemif))...)
+	case v1.DeviceInfoTypeAuxiliary:
+		allErrs = append(allErrs, validateAuxiliaryDevice(devInfo.Auxiliary, fldPath.Child(v1.DeviceInfoTypeAuxiliary))...)
+	case v1.DeviceInfoTypeAFXDP:
+		allErrs = append(allErrs, validateAFXDPDevice(devInfo.AFXDP, fldPath.Child(v1.DeviceInfoTypeAFXDP))...)
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("type"), devInfo.Type, supportedDeviceInfoTypes))
+	}
+
+	return allErrs
+}
+
+func validateDeviceInfoVersion(deviceType, version string, fldPath *field.Path) field.ErrorList {
+	if version == "" {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	v, err := utilversion.ParseSemantic(version)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, version, err.Error())}
+	}
+	if v.Major() != 1 {
+		return field.ErrorList{field.NotSupported(fldPath, version, supportedDeviceInfoVersions)}
+	}
+
+	if since, ok := typesSince[deviceType]; ok && v.LessThan(utilversion.MustParseSemantic(since)) {
+		return field.ErrorList{field.Invalid(fldPath, version,
+			"type "+deviceType+" requires version "+since+" or later")}
+	}
+	return nil
+}
+
+func validatePciDevice(pci *v1.PciDevice, fldPath *field.Path) field.ErrorList {
+	if pci == nil {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+
+	allErrs := validatePciAddress(pci.PciAddress, true, fldPath.Child("pci-address"))
+	allErrs = append(allErrs, validatePciAddress(pci.PfPciAddress, false, fldPath.Child("pf-pci-address"))...)
+	allErrs = append(allErrs, validateDevicePath(pci.Vhostnet, false, fldPath.Child("vhost-net"))...)
+	return allErrs
+}
+
+func validateVdpaDevice(vdpa *v1.VdpaDevice, fldPath *field.Path) field.ErrorList {
+	if vdpa == nil {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+
+	var allErrs field.ErrorList
+	if vdpa.ParentDevice == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("parent-device"), ""))
+	}
+	allErrs = append(allErrs, validateEnum(vdpa.Driver, false, supportedVdpaDrivers, fldPath.Child("driver"))...)
+	allErrs = append(allErrs, validateDevicePath(vdpa.Path, false, fldPath.Child("path"))...)
+	allErrs = append(allErrs, validatePciAddress(vdpa.PciAddress, false, fldPath.Child("pci-address"))...)
+	allErrs = append(allErrs, validatePciAddress(vdpa.PfPciAddress, false, fldPath.Child("pf-pci-address"))...)
+	return allErrs
+}
+
+func validateVhostDevice(vhost *v1.VhostDevice, fldPath *field.Path) field.ErrorList {
+	if vhost == nil {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+
+	allErrs := validateEnum(vhost.Mode, true, supportedVhostModes, fldPath.Child("mode"))
+	allErrs = append(allErrs, validateDevicePath(vhost.Path, true, fldPath.Child("path"))...)
+	return allErrs
+}
+
+func validateMemifDevice(memif *v1.MemifDevice, fldPath *field.Path) field.ErrorList {
+	if memif == nil {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+
+	allErrs := validateEnum(memif.Role, true, supportedMemifRoles, fldPath.Child("role"))
+	allErrs = append(allErrs, validateEnum(memif.Mode, false, supportedMemifModes, fldPath.Child("mode"))...)
+	allErrs = append(allErrs, validateDevicePath(memif.Path, true, fldPath.Child("path"))...)
+	return allErrs
+}
+
+func validateAuxiliaryDevice(aux *v1.AuxiliaryDevice, fldPath *field.Path) field.ErrorList {
+	if aux == nil {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+
+	var allErrs field.ErrorList
+	if aux.DeviceName == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("device-name"), ""))
+	}
+	allErrs = append(allErrs, validatePciAddress(aux.ParentPciAddress, true, fldPath.Child("parent-pci-address"))...)
+	allErrs = append(allErrs, validateNetdevName(aux.Representor, false, fldPath.Child("representor"))...)
+	return allErrs
+}
+
+func validateAFXDPDevice(afxdp *v1.AFXDPDevice, fldPath *field.Path) field.ErrorList {
+	if afxdp == nil {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+
+	allErrs := validateNetdevName(afxdp.Netdev, true, fldPath.Child("netdev"))
+	allErrs = append(allErrs, validateDevicePath(afxdp.Path, false, fldPath.Child("path"))...)
+	return allErrs
+}
+
+func validatePciAddress(address string, required bool, fldPath *field.Path) field.ErrorList {
+	if address == "" {
+		if required {
+			return field.ErrorList{field.Required(fldPath, "")}
+		}
+		return nil
+	}
+	if !pciAddressRegexp.MatchString(address) {
+		return field.ErrorList{field.Invalid(fldPath, address, "must be a PCI address in the form dddd:bb:dd.f")}
+	}
+	return nil
+}
+
+func validateDevicePath(path string, required bool, fldPath *field.Path) field.ErrorList {
+	if path == "" {
+		if required {
+			return field.ErrorList{field.Required(fldPath, "")}
+		}
+		return nil
+	}
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+		return field.ErrorList{field.Invalid(fldPath, path, "must be a clean absolute path")}
+	}
+	return nil
+}
+
+// validateNetdevName checks a Linux network device name: at most
+// IFNAMSIZ-1 characters, no slash, colon or whitespace, not "." or ".."
+func validateNetdevName(name string, required bool, fldPath *field.Path) field.ErrorList {
+	if name == "" {
+		if required {
+			return field.ErrorList{field.Required(fldPath, "")}
+		}
+		return nil
+	}
+	if len(name) > MaxInterfaceNameLength {
+		return field.ErrorList{field.TooLong(fldPath, name, MaxInterfaceNameLength)}
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, "/: \t\n") {
+		return field.ErrorList{field.Invalid(fldPath, name, "must be a valid network device name")}
+	}
+	return nil
+}
+
+func validateEnum(value string, required bool, supported []string, fldPath *field.Path) field.ErrorList {
+	if value == "" {
+		if required {
+			return field.ErrorList{field.Required(fldPath, "")}
+		}
+		return nil
+	}
+	for _, s := range supported {
+		if value == s {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotSupported(fldPath, value, supported)}
+}
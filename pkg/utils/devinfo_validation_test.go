@@ -0,0 +1,193 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Device Information validation", func() {
+	table.DescribeTable("accepts consistent device information",
+		func(devInfo *v1.DeviceInfo) {
+			Expect(ValidateDeviceInfo(devInfo)).To(Succeed())
+		},
+		table.Entry("pci", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypePCI,
+			Version: "1.0.0",
+			Pci: &v1.PciDevice{
+				PciAddress:   "0000:01:02.2",
+				PfPciAddress: "0000:01:00.0",
+				Vhostnet:     "/dev/vhost-net",
+			},
+		}),
+		table.Entry("pci with a v-prefixed version", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypePCI,
+			Version: "v1.0.0",
+			Pci:     &v1.PciDevice{PciAddress: "10000:e1:1f.7"},
+		}),
+		table.Entry("vdpa", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypeVDPA,
+			Version: "1.0.0",
+			Vdpa: &v1.VdpaDevice{
+				ParentDevice: "vdpa:0000:65:00.3",
+				Driver:       "vhost",
+				Path:         "/dev/vhost-vdpa-1",
+				PciAddress:   "0000:65:00.3",
+			},
+		}),
+		table.Entry("vhost-user", &v1.DeviceInfo{
+			Type:      v1.DeviceInfoTypeVHostUser,
+			Version:   "1.0.0",
+			VhostUser: &v1.VhostDevice{Mode: v1.VhostDeviceModeServer, Path: "/var/run/vhost/sock0"},
+		}),
+		table.Entry("memif", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypeMemif,
+			Version: "1.0.0",
+			Memif: &v1.MemifDevice{
+				Role: v1.MemifDeviceRoleMaster,
+				Mode: v1.MemifDeviceModeEthernet,
+				Path: "/var/run/memif/memif0.sock",
+			},
+		}),
+		table.Entry("auxiliary", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypeAuxiliary,
+			Version: v1.DeviceInfoVersion110,
+			Auxiliary: &v1.AuxiliaryDevice{
+				DeviceName:       "mlx5_core.sf.4",
+				ParentPciAddress: "0000:03:00.0",
+				Representor:      "pf0sf4",
+			},
+		}),
+		table.Entry("af-xdp", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypeAFXDP,
+			Version: v1.DeviceInfoVersion110,
+			AFXDP:   &v1.AFXDPDevice{Netdev: "ens801f0", Path: "/tmp/afxdp_dp/ens801f0/afxdp.sock"},
+		}),
+	)
+
+	table.DescribeTable("rejects inconsistent device information",
+		func(devInfo *v1.DeviceInfo, message string) {
+			err := ValidateDeviceInfo(devInfo)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(message))
+		},
+		table.Entry("nil", nil, "device-info: Required value"),
+		table.Entry("missing type", &v1.DeviceInfo{Version: "1.0.0"}, "device-info.type: Required value"),
+		table.Entry("unknown type", &v1.DeviceInfo{Type: "usb", Version: "1.0.0"}, `device-info.type: Unsupported value: "usb"`),
+		table.Entry("missing version", &v1.DeviceInfo{
+			Type: v1.DeviceInfoTypePCI,
+			Pci:  &v1.PciDevice{PciAddress: "0000:01:02.2"},
+		}, "device-info.version: Required value"),
+		table.Entry("unsupported major version", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypePCI,
+			Version: "2.0.0",
+			Pci:     &v1.PciDevice{PciAddress: "0000:01:02.2"},
+		}, `device-info.version: Unsupported value: "2.0.0"`),
+		table.Entry("pci without pci block", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypePCI,
+			Version: "1.0.0",
+		}, "device-info.pci: Required value"),
+		table.Entry("malformed pci address", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypePCI,
+			Version: "1.0.0",
+			Pci:     &v1.PciDevice{PciAddress: "01:02.2"},
+		}, `device-info.pci.pci-address: Invalid value: "01:02.2"`),
+		table.Entry("block of another type", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypePCI,
+			Version: "1.0.0",
+			Pci:     &v1.PciDevice{PciAddress: "0000:01:02.2"},
+			Memif:   &v1.MemifDevice{Role: v1.MemifDeviceRoleMaster, Path: "/run/memif.sock"},
+		}, "device-info.memif: Forbidden"),
+		table.Entry("unknown vhost-user mode", &v1.DeviceInfo{
+			Type:      v1.DeviceInfoTypeVHostUser,
+			Version:   "1.0.0",
+			VhostUser: &v1.VhostDevice{Mode: "proxy", Path: "/var/run/vhost/sock0"},
+		}, `device-info.vhost-user.mode: Unsupported value: "proxy"`),
+		table.Entry("relative socket path", &v1.DeviceInfo{
+			Type:      v1.DeviceInfoTypeVHostUser,
+			Version:   "1.0.0",
+			VhostUser: &v1.VhostDevice{Mode: v1.VhostDeviceModeClient, Path: "sock0"},
+		}, `device-info.vhost-user.path: Invalid value: "sock0"`),
+		table.Entry("unknown memif role", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypeMemif,
+			Version: "1.0.0",
+			Memif:   &v1.MemifDevice{Role: "leader", Path: "/var/run/memif/memif0.sock"},
+		}, `device-info.memif.role: Unsupported value: "leader"`),
+		table.Entry("unknown vdpa driver", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypeVDPA,
+			Version: "1.0.0",
+			Vdpa:    &v1.VdpaDevice{ParentDevice: "vdpa0", Driver: "vfio"},
+		}, `device-info.vdpa.driver: Unsupported value: "vfio"`),
+		table.Entry("auxiliary with a version predating it", &v1.DeviceInfo{
+			Type:      v1.DeviceInfoTypeAuxiliary,
+			Version:   v1.DeviceInfoVersion100,
+			Auxiliary: &v1.AuxiliaryDevice{DeviceName: "mlx5_core.sf.4", ParentPciAddress: "0000:03:00.0"},
+		}, "type auxiliary requires version 1.1.0 or later"),
+		table.Entry("auxiliary without parent", &v1.DeviceInfo{
+			Type:      v1.DeviceInfoTypeAuxiliary,
+			Version:   v1.DeviceInfoVersion110,
+			Auxiliary: &v1.AuxiliaryDevice{DeviceName: "mlx5_core.sf.4"},
+		}, "device-info.auxiliary.parent-pci-address: Required value"),
+		table.Entry("af-xdp with an overlong netdev", &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypeAFXDP,
+			Version: v1.DeviceInfoVersion110,
+			AFXDP:   &v1.AFXDPDevice{Netdev: "averyveryverylongname"},
+		}, "device-info.af-xdp.netdev: Too long"),
+	)
+
+	Context("stores enforcing validation", func() {
+		var tmpDir string
+		invalid := &v1.DeviceInfo{Type: v1.DeviceInfoTypePCI, Version: "1.0.0"}
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = ioutil.TempDir("", "devinfo")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(tmpDir)).To(Succeed())
+		})
+
+		It("refuse to save invalid device information", func() {
+			for _, store := range []DeviceInfoStore{
+				NewFSDeviceInfoStore(tmpDir, WithDeviceInfoValidation()),
+				NewMemDeviceInfoStore(WithDeviceInfoValidation()),
+			} {
+				path := store.CNIDeviceInfoPath("invalid")
+				Expect(store.Save(path, invalid)).To(MatchError(ContainSubstring("invalid Device Information")))
+				_, err := store.Load(path)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			}
+		})
+
+		It("refuse to load invalid device information", func() {
+			path := NewFSDeviceInfoStore(tmpDir).CNIDeviceInfoPath("invalid")
+			Expect(NewFSDeviceInfoStore(tmpDir).Save(path, invalid)).To(Succeed())
+
+			_, err := NewFSDeviceInfoStore(tmpDir, WithDeviceInfoValidation()).Load(path)
+			Expect(err).To(MatchError(ContainSubstring("device-info.pci: Required value")))
+			Expect(err).To(MatchError(ContainSubstring(filepath.Base(path))))
+		})
+	})
+})
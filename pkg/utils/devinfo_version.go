@@ -0,0 +1,153 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+// UnsupportedDeviceInfoVersionError indicates Device Information written in
+// a major version this client does not know how to decode
+type UnsupportedDeviceInfoVersionError struct {
+	Version string
+}
+
+func (e *UnsupportedDeviceInfoVersionError) Error() string {
+	return fmt.Sprintf("unsupported Device Information version %q: supported versions are %v", e.Version, supportedDeviceInfoVersions)
+}
+
+// typesSince maps DeviceInfo types to the version that introduced them.
+// Types not listed exist since DeviceInfoVersion100.
+var typesSince = map[string]string{
+	v1.DeviceInfoTypeAuxiliary: v1.DeviceInfoVersion110,
+	v1.DeviceInfoTypeAFXDP:     v1.DeviceInfoVersion110,
+}
+
+// parseDeviceInfoVersion parses a Device Information version. Payloads
+// written before versioning was introduced carry no version and are treated
+// as DeviceInfoVersion100.
+func parseDeviceInfoVersion(version string) (*utilversion.Version, error) {
+	if version == "" {
+		version = v1.DeviceInfoVersion100
+	}
+	v, err := utilversion.ParseSemantic(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Device Information version %q: %v", version, err)
+	}
+	if v.Major() != 1 {
+		return nil, &UnsupportedDeviceInfoVersionError{Version: version}
+	}
+	return v, nil
+}
+
+// DecodeDeviceInfo decodes Device Information of any supported version and
+// upgrades it to DeviceInfoVersion. An *UnsupportedDeviceInfoVersionError is
+// returned for payloads of an unknown major version, and an error for null
+// payloads, payloads without a type or with a type newer than their version.
+func DecodeDeviceInfo(data []byte) (*v1.DeviceInfo, error) {
+	devInfo, err := decodeDeviceInfoAsWritten(data)
+	if err != nil {
+		return nil, err
+	}
+	devInfo.Version = v1.DeviceInfoVersion
+	return devInfo, nil
+}
+
+// decodeDeviceInfoAsWritten decodes Device Information of any supported
+// version, keeping the version it was written in
+func decodeDeviceInfoAsWritten(data []byte) (*v1.DeviceInfo, error) {
+	var header *struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("Device Information is null")
+	}
+	v, err := parseDeviceInfoVersion(header.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every 1.x version is a superset of the previous one, so the current
+	// struct decodes all of them.
+	devInfo := &v1.DeviceInfo{}
+	if err := json.Unmarshal(data, devInfo); err != nil {
+		return nil, err
+	}
+	if devInfo.Version == "" {
+		devInfo.Version = v1.DeviceInfoVersion100
+	}
+	if devInfo.Type == "" {
+		return nil, fmt.Errorf("Device Information has no type")
+	}
+	if err := checkDeviceInfoTypeVersion(devInfo.Type, devInfo.Version, v); err != nil {
+		return nil, err
+	}
+
+	return devInfo, nil
+}
+
+// checkDeviceInfoTypeVersion fails if deviceType does not exist in version v
+func checkDeviceInfoTypeVersion(deviceType, version string, v *utilversion.Version) error {
+	if since, ok := typesSince[deviceType]; ok && v.LessThan(utilversion.MustParseSemantic(since)) {
+		return fmt.Errorf("Device Information type %s does not exist in version %s: it requires version %s or later",
+			deviceType, version, since)
+	}
+	return nil
+}
+
+// EncodeDeviceInfo encodes devInfo in the given version so that older
+// consumers can read it. An empty version keeps the version set in devInfo,
+// or DeviceInfoVersion if there is none. Encoding fails if the device type
+// does not exist in the requested version.
+func EncodeDeviceInfo(devInfo *v1.DeviceInfo, version string) ([]byte, error) {
+	out, err := deviceInfoInVersion(devInfo, version)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// deviceInfoInVersion returns a copy of devInfo as EncodeDeviceInfo writes it
+func deviceInfoInVersion(devInfo *v1.DeviceInfo, version string) (*v1.DeviceInfo, error) {
+	if devInfo == nil {
+		return nil, fmt.Errorf("Device Information is null")
+	}
+
+	if version == "" {
+		version = devInfo.Version
+	}
+	if version == "" {
+		version = v1.DeviceInfoVersion
+	}
+	v, err := parseDeviceInfoVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDeviceInfoTypeVersion(devInfo.Type, version, v); err != nil {
+		return nil, fmt.Errorf("cannot encode: %v", err)
+	}
+
+	out := devInfo.DeepCopy()
+	out.Version = version
+	return out, nil
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Device Information versions", func() {
+	It("upgrades older payloads to the current version", func() {
+		devInfo, err := DecodeDeviceInfo([]byte(`{"type":"pci","version":"1.0.0","pci":{"pci-address":"0000:01:02.2"}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devInfo.Version).To(Equal(v1.DeviceInfoVersion))
+		Expect(devInfo.Pci.PciAddress).To(Equal("0000:01:02.2"))
+	})
+
+	It("treats unversioned payloads as version 1.0.0", func() {
+		devInfo, err := DecodeDeviceInfo([]byte(`{"type":"pci","pci":{"pci-address":"0000:01:02.2"}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devInfo.Version).To(Equal(v1.DeviceInfoVersion))
+	})
+
+	It("rejects unknown major versions with a typed error", func() {
+		_, err := DecodeDeviceInfo([]byte(`{"type":"pci","version":"2.0.0","pci":{"pci-address":"0000:01:02.2"}}`))
+		var versionErr *UnsupportedDeviceInfoVersionError
+		Expect(errors.As(err, &versionErr)).To(BeTrue())
+		Expect(versionErr.Version).To(Equal("2.0.0"))
+
+		_, err = DecodeDeviceInfo([]byte(`{"type":"pci","version":"one"}`))
+		Expect(err).To(MatchError(ContainSubstring("invalid Device Information version")))
+	})
+
+	It("rejects types newer than the payload version", func() {
+		for _, data := range []string{
+			`{"type":"auxiliary","version":"1.0.0","auxiliary":{"device-name":"mlx5_core.sf.4","parent-pci-address":"0000:03:00.0"}}`,
+			`{"type":"af-xdp","af-xdp":{"netdev":"ens801f0"}}`,
+		} {
+			_, err := DecodeDeviceInfo([]byte(data))
+			Expect(err).To(MatchError(ContainSubstring("requires version 1.1.0 or later")))
+		}
+	})
+
+	It("rejects null payloads and payloads without a type", func() {
+		_, err := DecodeDeviceInfo([]byte(`null`))
+		Expect(err).To(MatchError("Device Information is null"))
+		_, err = DecodeDeviceInfo([]byte(`{"version":"1.1.0"}`))
+		Expect(err).To(MatchError("Device Information has no type"))
+	})
+
+	It("copies what validating stores load", func() {
+		store := NewMemDeviceInfoStore(WithDeviceInfoValidation())
+		SetDefaultDeviceInfoStore(store)
+		defer SetDefaultDeviceInfoStore(nil)
+
+		Expect(store.Save(store.DPDeviceInfoPath("example.com/sriov", "0000:01:02.2"), &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypePCI,
+			Version: v1.DeviceInfoVersion100,
+			Pci:     &v1.PciDevice{PciAddress: "0000:01:02.2"},
+		})).To(Succeed())
+		_, err := store.Load(store.DPDeviceInfoPath("example.com/sriov", "0000:01:02.2"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(CopyDeviceInfoForCNIFromDP(store.CNIDeviceInfoPath("net1"), "example.com/sriov", "0000:01:02.2")).To(Succeed())
+	})
+
+	It("encodes in the requested version", func() {
+		pci := &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypePCI,
+			Version: v1.DeviceInfoVersion,
+			Pci:     &v1.PciDevice{PciAddress: "0000:01:02.2"},
+		}
+		data, err := EncodeDeviceInfo(pci, v1.DeviceInfoVersion100)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(`"version":"1.0.0"`))
+		Expect(pci.Version).To(Equal(v1.DeviceInfoVersion))
+
+		data, err = EncodeDeviceInfo(&v1.DeviceInfo{Type: v1.DeviceInfoTypePCI}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(`"version":"` + v1.DeviceInfoVersion + `"`))
+
+		aux := &v1.DeviceInfo{
+			Type:      v1.DeviceInfoTypeAuxiliary,
+			Version:   v1.DeviceInfoVersion,
+			Auxiliary: &v1.AuxiliaryDevice{DeviceName: "mlx5_core.sf.4", ParentPciAddress: "0000:03:00.0"},
+		}
+		_, err = EncodeDeviceInfo(aux, v1.DeviceInfoVersion100)
+		Expect(err).To(MatchError(ContainSubstring("requires version 1.1.0 or later")))
+		_, err = EncodeDeviceInfo(aux, "2.0.0")
+		Expect(err).To(BeAssignableToTypeOf(&UnsupportedDeviceInfoVersionError{}))
+	})
+
+	It("lets stores write an older version", func() {
+		tmpDir, err := ioutil.TempDir("", "devinfo")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+
+		store := NewFSDeviceInfoStore(tmpDir, WithDeviceInfoVersion(v1.DeviceInfoVersion100))
+		path := store.CNIDeviceInfoPath("net1")
+		Expect(store.Save(path, &v1.DeviceInfo{
+			Type:    v1.DeviceInfoTypePCI,
+			Version: v1.DeviceInfoVersion,
+			Pci:     &v1.PciDevice{PciAddress: "0000:01:02.2"},
+		})).To(Succeed())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		var onDisk v1.DeviceInfo
+		Expect(json.Unmarshal(data, &onDisk)).To(Succeed())
+		Expect(onDisk.Version).To(Equal(v1.DeviceInfoVersion100))
+
+		loaded, err := store.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Version).To(Equal(v1.DeviceInfoVersion))
+	})
+
+	It("validates the version validating stores write", func() {
+		tmpDir, err := ioutil.TempDir("", "devinfo")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+
+		for _, store := range []DeviceInfoStore{
+			NewFSDeviceInfoStore(tmpDir, WithDeviceInfoValidation()),
+			NewMemDeviceInfoStore(WithDeviceInfoValidation()),
+		} {
+			path := store.CNIDeviceInfoPath("net1")
+			Expect(store.Save(path, &v1.DeviceInfo{
+				Type: v1.DeviceInfoTypePCI,
+				Pci:  &v1.PciDevice{PciAddress: "0000:01:02.2"},
+			})).To(Succeed())
+
+			written, err := loadDeviceInfoAsWritten(store, path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(written.Version).To(Equal(v1.DeviceInfoVersion))
+		}
+	})
+})
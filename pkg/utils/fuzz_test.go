@@ -0,0 +1,212 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+func FuzzParseNetworkAnnotation(f *testing.F) {
+	for _, seed := range []string{
+		"net-a",
+		" ns1 / net-a @ eth1 ,net-b@net2",
+		"net-a, ns1/Net-b",
+		"a/b/c",
+		"net-a@eth0@eth1",
+		"net-a,,net-b",
+		"/net-a",
+		"net-a@",
+		"nét",
+		`[{"name": "net-a", "namespace": "ns1", "interface": "eth1"}]`,
+		`[{"name":`,
+		`[null]`,
+		`[{}]`,
+		`[{"name": "net-a"}, null]`,
+		`null`,
+		"",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, annotation string) {
+		networks, err := ParseNetworkAnnotation(annotation, "default")
+		if annotation == "" {
+			return
+		}
+		if strings.IndexAny(annotation, "[{\"") >= 0 {
+			// JSON annotations must not panic and must not yield null elements
+			for _, network := range networks {
+				if network == nil {
+					t.Fatalf("%q: null network without error", annotation)
+				}
+			}
+			return
+		}
+
+		if err != nil {
+			var selectionErr *NetworkSelectionError
+			if !errors.As(err, &selectionErr) {
+				t.Fatalf("%q: unexpected error type %T: %v", annotation, errors.Unwrap(err), err)
+			}
+			if selectionErr.Offset >= len(selectionErr.Item) && selectionErr.Item != "" &&
+				selectionErr.Reason != "must not be empty" {
+				t.Fatalf("%q: offset %d out of item %q", annotation, selectionErr.Offset, selectionErr.Item)
+			}
+			return
+		}
+
+		if len(networks) != strings.Count(annotation, ",")+1 {
+			t.Fatalf("%q: parsed %d networks", annotation, len(networks))
+		}
+		for _, network := range networks {
+			for _, field := range []string{network.Namespace, network.Name, network.InterfaceRequest} {
+				if strings.Trim(field, "abcdefghijklmnopqrstuvwxyz0123456789-") != "" {
+					t.Fatalf("%q: invalid character in %q", annotation, field)
+				}
+			}
+			if network.Name == "" || len(network.Namespace) > 63 || len(network.Name) > 253 || len(network.InterfaceRequest) > MaxInterfaceNameLength {
+				t.Fatalf("%q: invalid network %+v", annotation, network)
+			}
+		}
+	})
+}
+
+func FuzzGetCNIConfigFromSpec(f *testing.F) {
+	for _, seed := range []string{
+		`{"type": "test", "name": "testname", "version": "0.3.1"}`,
+		`{"type": "test", "version": "0.3.1"}`,
+		`{"name": "", "version": "0.3.1", "plugins": [{"type": "test"}]}`,
+		`***invalid json file***`,
+		`null`,
+		`[]`,
+		`"config"`,
+		` \n\t`,
+		``,
+	} {
+		f.Add(seed, "test-net-attach-def")
+	}
+
+	f.Fuzz(func(t *testing.T, configData, netName string) {
+		config, err := GetCNIConfigFromSpec(configData, netName)
+		if err != nil {
+			var value interface{}
+			var emptyErr *EmptySpecConfigError
+			var nonObjectErr *NonObjectSpecConfigError
+			switch {
+			case strings.TrimSpace(configData) == "":
+				if !errors.As(err, &emptyErr) {
+					t.Fatalf("%q: expected an EmptySpecConfigError, got %v", configData, err)
+				}
+			case json.Unmarshal([]byte(configData), &value) == nil:
+				if !errors.As(err, &nonObjectErr) {
+					t.Fatalf("%q: expected a NonObjectSpecConfigError, got %v", configData, err)
+				}
+			}
+			return
+		}
+		var rawConfig map[string]interface{}
+		if err := json.Unmarshal(config, &rawConfig); err != nil || rawConfig == nil {
+			t.Fatalf("%q: returned config %q is not a JSON object: %v", configData, config, err)
+		}
+		if _, ok := rawConfig["name"]; !ok {
+			t.Fatalf("%q: returned config %q has no name", configData, config)
+		}
+	})
+}
+
+func FuzzGetNetworkStatus(f *testing.F) {
+	for _, seed := range []string{
+		`[{"name": "cbr0", "interface": "eth0", "ips": ["10.244.1.2"], "default": true}]`,
+		`[{"name": "ns1/net-a", "interface": "net1", "ips": ["1.1.1.1"], "mac": "ee:ee:ee:ee:ee:ee",
+			"dns": {"nameservers": ["10.1.1.1"]}, "device-info": {"type": "pci", "version": "1.1.0", "pci": {"pci-address": "0000:03:00.1"}}}]`,
+		`{`,
+		`null`,
+		`[null]`,
+		``,
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, annotation string) {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod1",
+			Namespace:   "ns1",
+			Annotations: map[string]string{v1.NetworkStatusAnnot: annotation},
+		}}
+		statuses, err := GetNetworkStatus(pod)
+		if err != nil {
+			return
+		}
+		if _, err := encodeNetworkStatus(statuses); err != nil {
+			t.Fatalf("%q: cannot encode the decoded status: %v", annotation, err)
+		}
+		list, err := GetNetworkStatusList(pod)
+		if err != nil {
+			t.Fatalf("%q: GetNetworkStatusList failed after GetNetworkStatus succeeded: %v", annotation, err)
+		}
+		list.Default().IPv4()
+		list.ByInterface("net1").IPv6()
+	})
+}
+
+func FuzzLoadDeviceInfo(f *testing.F) {
+	for _, seed := range []string{
+		`{"type":"pci","version":"1.0.0","pci":{"pci-address":"0000:01:02.2"}}`,
+		`{"type":"pci","pci":{"pci-address":"0000:01:02.2"}}`,
+		`{"type":"pci","version":"2.0.0","pci":{"pci-address":"0000:01:02.2"}}`,
+		`{"type":"pci","version":"one"}`,
+		`{"type":"auxiliary","version":"1.1.0","auxiliary":{"device-name":"mlx5_core.sf.4","parent-pci-address":"0000:03:00.0"}}`,
+		`{"type":"auxiliary","version":"1.0.0","auxiliary":{"device-name":"mlx5_core.sf.4","parent-pci-address":"0000:03:00.0"}}`,
+		`{"type":"af-xdp","af-xdp":{"netdev":"ens801f0"}}`,
+		`{"version":"1.1.0"}`,
+		`{}`,
+		`null`,
+		``,
+	} {
+		f.Add([]byte(seed), true)
+	}
+
+	dir := f.TempDir()
+	f.Fuzz(func(t *testing.T, data []byte, validate bool) {
+		path := filepath.Join(dir, "device.json")
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		opts := deviceInfoOptions{validate: validate}
+		devInfo, err := loadDeviceInfo(path, opts)
+		if err != nil {
+			return
+		}
+		if devInfo == nil || devInfo.Type == "" {
+			t.Fatalf("%q: no Device Information type and no error", data)
+		}
+		if devInfo.Version != v1.DeviceInfoVersion {
+			t.Fatalf("%q: decoded version %q, expected %q", data, devInfo.Version, v1.DeviceInfoVersion)
+		}
+		if _, err := opts.encode(devInfo); err != nil {
+			t.Fatalf("%q: cannot encode the decoded Device Information: %v", data, err)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"net"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	cni100 "github.com/containernetworking/cni/pkg/types/100"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+// GatewayMismatchError indicates a CNI result that did not install the
+// default route requested by a network selection element
+type GatewayMismatchError struct {
+	// Network is the namespace/name of the network
+	Network string
+	// Requested is the requested gateway
+	Requested net.IP
+	// Installed are the gateways of the default routes in the result
+	Installed []net.IP
+}
+
+func (e *GatewayMismatchError) Error() string {
+	return fmt.Sprintf("network %s requested default route via %s, but the result installed %v",
+		e.Network, e.Requested, e.Installed)
+}
+
+// ipFamily returns "IPv4" or "IPv6"
+func ipFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return "IPv4"
+	}
+	return "IPv6"
+}
+
+// ValidateGatewayRequests checks that at most one of the networks of a pod
+// requests the default route of each IP family
+func ValidateGatewayRequests(networks []*v1.NetworkSelectionElement) error {
+	requestedBy := map[string]string{}
+	for _, network := range networks {
+		name := fmt.Sprintf("%s/%s", network.Namespace, network.Name)
+		for _, gw := range network.GatewayRequest {
+			if gw == nil || (gw.To4() == nil && gw.To16() == nil) {
+				return fmt.Errorf("network %s requests an invalid default route gateway", name)
+			}
+			family := ipFamily(gw)
+			if other, ok := requestedBy[family]; ok {
+				return fmt.Errorf("network %s requests the %s default route, which is already requested by network %s",
+					name, family, other)
+			}
+			requestedBy[family] = name
+		}
+	}
+	return nil
+}
+
+// VerifyGatewayRequest checks that result installed a default route via
+// each gateway requested by network. A *GatewayMismatchError is returned for
+// the first one missing.
+func VerifyGatewayRequest(network *v1.NetworkSelectionElement, result cnitypes.Result) error {
+	if network == nil || len(network.GatewayRequest) == 0 {
+		return nil
+	}
+
+	res, err := cni100.NewResultFromResult(result)
+	if err != nil {
+		return fmt.Errorf("error convert the type.Result to cni100.Result: %v", err)
+	}
+
+	var installed []net.IP
+	for _, route := range res.Routes {
+		if isDefaultRoute(route) && route.GW != nil {
+			installed = append(installed, route.GW)
+		}
+	}
+
+	for _, gw := range network.GatewayRequest {
+		found := false
+		for _, ip := range installed {
+			if ip.Equal(gw) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &GatewayMismatchError{
+				Network:   fmt.Sprintf("%s/%s", network.Namespace, network.Name),
+				Requested: gw,
+				Installed: installed,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"net"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	cni100 "github.com/containernetworking/cni/pkg/types/100"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Default route requests", func() {
+	It("accepts one default route request per IP family", func() {
+		Expect(ValidateGatewayRequests([]*v1.NetworkSelectionElement{
+			{Name: "net-a", Namespace: "ns", GatewayRequest: []net.IP{net.ParseIP("10.1.1.1")}},
+			{Name: "net-b", Namespace: "ns", GatewayRequest: []net.IP{net.ParseIP("2001:db8::1")}},
+			{Name: "net-c", Namespace: "ns"},
+		})).To(Succeed())
+	})
+
+	It("rejects two default route requests of the same IP family", func() {
+		err := ValidateGatewayRequests([]*v1.NetworkSelectionElement{
+			{Name: "net-a", Namespace: "ns", GatewayRequest: []net.IP{net.ParseIP("10.1.1.1")}},
+			{Name: "net-b", Namespace: "ns", GatewayRequest: []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("10.2.2.1")}},
+		})
+		Expect(err).To(MatchError("network ns/net-b requests the IPv4 default route, which is already requested by network ns/net-a"))
+
+		err = ValidateGatewayRequests([]*v1.NetworkSelectionElement{
+			{Name: "net-a", Namespace: "ns", GatewayRequest: []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2")}},
+		})
+		Expect(err).To(MatchError(ContainSubstring("IPv6 default route")))
+	})
+
+	Context("verifying CNI results", func() {
+		network := &v1.NetworkSelectionElement{
+			Name:           "net-a",
+			Namespace:      "ns",
+			GatewayRequest: []net.IP{net.ParseIP("10.1.1.1")},
+		}
+
+		It("succeeds if the requested default route was installed", func() {
+			result := &cni100.Result{
+				CNIVersion: "1.0.0",
+				Routes: []*cnitypes.Route{
+					{Dst: *EnsureCIDR("10.0.0.0/8"), GW: net.ParseIP("10.1.1.254")},
+					{Dst: *EnsureCIDR("0.0.0.0/0"), GW: net.ParseIP("10.1.1.1")},
+				},
+			}
+			Expect(VerifyGatewayRequest(network, result)).To(Succeed())
+			Expect(VerifyGatewayRequest(&v1.NetworkSelectionElement{Name: "net-b"}, result)).To(Succeed())
+		})
+
+		It("returns a typed error if it was not", func() {
+			result := &cni100.Result{
+				CNIVersion: "1.0.0",
+				Routes: []*cnitypes.Route{
+					{Dst: *EnsureCIDR("10.1.1.0/24"), GW: net.ParseIP("10.1.1.1")},
+					{Dst: *EnsureCIDR("0.0.0.0/0"), GW: net.ParseIP("10.1.1.254")},
+				},
+			}
+			err := VerifyGatewayRequest(network, result)
+			var mismatch *GatewayMismatchError
+			Expect(errors.As(err, &mismatch)).To(BeTrue())
+			Expect(mismatch.Network).To(Equal("ns/net-a"))
+			Expect(mismatch.Requested.String()).To(Equal("10.1.1.1"))
+			Expect(mismatch.Installed).To(HaveLen(1))
+			Expect(err).To(MatchError("network ns/net-a requested default route via 10.1.1.1, but the result installed [10.1.1.254]"))
+		})
+	})
+})
@@ -0,0 +1,251 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+const (
+	// IPAMTypeHostLocal is the type of the host-local IPAM plugin
+	IPAMTypeHostLocal = "host-local"
+	// IPAMTypeWhereabouts is the type of the whereabouts IPAM plugin
+	IPAMTypeWhereabouts = "whereabouts"
+)
+
+// IPAMRange is an address range of an IPAM configuration
+type IPAMRange struct {
+	Subnet *net.IPNet
+	// RangeStart and RangeEnd optionally narrow the range inside Subnet
+	RangeStart net.IP
+	RangeEnd   net.IP
+	// Exclude are the subnets never allocated from the range
+	Exclude []*net.IPNet
+}
+
+// Contains returns true if ip can be allocated from the range
+func (r *IPAMRange) Contains(ip net.IP) bool {
+	if !r.Subnet.Contains(ip) {
+		return false
+	}
+	// compare the 16-byte forms so that IPv4 addresses compare bytewise
+	// however they were parsed
+	if r.RangeStart != nil && bytes.Compare(ip.To16(), r.RangeStart.To16()) < 0 {
+		return false
+	}
+	if r.RangeEnd != nil && bytes.Compare(ip.To16(), r.RangeEnd.To16()) > 0 {
+		return false
+	}
+	return !r.excludes(ip)
+}
+
+func (r *IPAMRange) excludes(ip net.IP) bool {
+	for _, exclude := range r.Exclude {
+		if exclude.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IPAMConfig is the part of an IPAM configuration needed to validate IP
+// requests
+type IPAMConfig struct {
+	Type   string
+	Ranges []IPAMRange
+}
+
+// ipamRangeConf is a host-local range
+type ipamRangeConf struct {
+	Subnet     string `json:"subnet"`
+	RangeStart string `json:"rangeStart"`
+	RangeEnd   string `json:"rangeEnd"`
+}
+
+// whereaboutsRangeConf is a whereabouts range
+type whereaboutsRangeConf struct {
+	Range      string   `json:"range"`
+	RangeStart string   `json:"range_start"`
+	RangeEnd   string   `json:"range_end"`
+	Exclude    []string `json:"exclude"`
+}
+
+type ipamConf struct {
+	Type string `json:"type"`
+
+	// host-local
+	ipamRangeConf
+	Ranges [][]ipamRangeConf `json:"ranges"`
+
+	// whereabouts
+	whereaboutsRangeConf
+	IPRanges []whereaboutsRangeConf `json:"ipRanges"`
+}
+
+// ParseIPAMConfig extracts the IPAM ranges of a CNI configuration or
+// configuration list. It returns nil if no plugin has an IPAM section, and an
+// IPAMConfig without ranges if the IPAM type is neither host-local nor
+// whereabouts.
+func ParseIPAMConfig(config []byte) (*IPAMConfig, error) {
+	var raw struct {
+		IPAM    *ipamConf `json:"ipam"`
+		Plugins []struct {
+			IPAM *ipamConf `json:"ipam"`
+		} `json:"plugins"`
+	}
+	if err := json.Unmarshal(config, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CNI config: %v", err)
+	}
+
+	conf := raw.IPAM
+	for i := 0; conf == nil && i < len(raw.Plugins); i++ {
+		conf = raw.Plugins[i].IPAM
+	}
+	if conf == nil {
+		return nil, nil
+	}
+
+	ipam := &IPAMConfig{Type: conf.Type}
+	switch conf.Type {
+	case IPAMTypeHostLocal:
+		rangeConfs := []ipamRangeConf{}
+		if conf.Subnet != "" {
+			rangeConfs = append(rangeConfs, conf.ipamRangeConf)
+		}
+		for _, set := range conf.Ranges {
+			rangeConfs = append(rangeConfs, set...)
+		}
+		for _, rc := range rangeConfs {
+			r, err := parseIPAMRange(rc.Subnet, rc.RangeStart, rc.RangeEnd, nil)
+			if err != nil {
+				return nil, err
+			}
+			ipam.Ranges = append(ipam.Ranges, *r)
+		}
+	case IPAMTypeWhereabouts:
+		rangeConfs := []whereaboutsRangeConf{}
+		if conf.Range != "" {
+			rangeConfs = append(rangeConfs, conf.whereaboutsRangeConf)
+		}
+		rangeConfs = append(rangeConfs, conf.IPRanges...)
+		for _, rc := range rangeConfs {
+			subnet, start, end := rc.Range, rc.RangeStart, rc.RangeEnd
+			// whereabouts also accepts "<start>-<end>/<prefix>"
+			if i := strings.Index(subnet, "-"); i >= 0 && strings.Contains(subnet[i:], "/") {
+				prefix := subnet[strings.Index(subnet, "/"):]
+				start, end = subnet[:i], strings.TrimSuffix(subnet[i+1:], prefix)
+				subnet = start + prefix
+			}
+			r, err := parseIPAMRange(subnet, start, end, rc.Exclude)
+			if err != nil {
+				return nil, err
+			}
+			ipam.Ranges = append(ipam.Ranges, *r)
+		}
+	}
+	return ipam, nil
+}
+
+func parseIPAMRange(subnet, start, end string, exclude []string) (*IPAMRange, error) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IPAM subnet %q: %v", subnet, err)
+	}
+	r := &IPAMRange{Subnet: ipNet}
+	if start != "" {
+		if r.RangeStart = net.ParseIP(start); r.RangeStart == nil {
+			return nil, fmt.Errorf("invalid IPAM range start %q", start)
+		}
+	}
+	if end != "" {
+		if r.RangeEnd = net.ParseIP(end); r.RangeEnd == nil {
+			return nil, fmt.Errorf("invalid IPAM range end %q", end)
+		}
+	}
+	for _, cidr := range exclude {
+		_, excludeNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IPAM exclude %q: %v", cidr, err)
+		}
+		r.Exclude = append(r.Exclude, excludeNet)
+	}
+	return r, nil
+}
+
+// ValidateIPRequest checks that the IPs requested for a network, plain or
+// in CIDR notation, can be allocated by the IPAM configuration of the
+// NetworkAttachmentDefinition: each one must fall inside one of its ranges
+// without being excluded. Requests on configurations whose ranges are not
+// known, e.g. with an empty Spec, see IsEmptySpecConfig, or another IPAM
+// type, are not checked.
+// The returned error aggregates all problems found.
+func ValidateIPRequest(netAttachDef *v1.NetworkAttachmentDefinition, ips []string) error {
+	if netAttachDef == nil {
+		return fmt.Errorf("no network attachment definition set")
+	}
+	if len(ips) == 0 || IsEmptySpecConfig(netAttachDef.Spec.Config) {
+		return nil
+	}
+
+	ipam, err := ParseIPAMConfig([]byte(netAttachDef.Spec.Config))
+	if err != nil {
+		return err
+	}
+	if ipam == nil || len(ipam.Ranges) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, request := range ips {
+		ip := net.ParseIP(request)
+		if ip == nil {
+			var err error
+			if ip, _, err = net.ParseCIDR(request); err != nil {
+				errs = append(errs, fmt.Errorf("requested IP %q is not an IP address", request))
+				continue
+			}
+		}
+
+		inside, excluded := false, false
+		for i := range ipam.Ranges {
+			r := &ipam.Ranges[i]
+			if r.Contains(ip) {
+				inside = true
+				break
+			}
+			if r.Subnet.Contains(ip) && r.excludes(ip) {
+				excluded = true
+			}
+		}
+		switch {
+		case inside:
+		case excluded:
+			errs = append(errs, fmt.Errorf("requested IP %s is excluded from the %s range of network %s",
+				ip, ipam.Type, netAttachDef.Name))
+		default:
+			errs = append(errs, fmt.Errorf("requested IP %s is outside the %s range of network %s",
+				ip, ipam.Type, netAttachDef.Name))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
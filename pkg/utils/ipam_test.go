@@ -0,0 +1,121 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"net"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func newNetAttachDef(config string) *v1.NetworkAttachmentDefinition {
+	return &v1.NetworkAttachmentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "net-a", Namespace: "ns"},
+		Spec:       v1.NetworkAttachmentDefinitionSpec{Config: config},
+	}
+}
+
+var _ = Describe("IPAM ranges", func() {
+	const hostLocal = `{
+		"cniVersion": "0.4.0",
+		"name": "net-a",
+		"type": "macvlan",
+		"ipam": {
+			"type": "host-local",
+			"ranges": [
+				[{"subnet": "10.1.0.0/24", "rangeStart": "10.1.0.10", "rangeEnd": "10.1.0.20"}],
+				[{"subnet": "2001:db8::/64"}]
+			]
+		}
+	}`
+	const whereabouts = `{
+		"cniVersion": "0.4.0",
+		"name": "net-a",
+		"plugins": [{
+			"type": "macvlan",
+			"ipam": {
+				"type": "whereabouts",
+				"range": "192.168.2.0/24",
+				"exclude": ["192.168.2.0/28"],
+				"ipRanges": [{"range": "10.2.0.100-10.2.0.110/24"}]
+			}
+		}]
+	}`
+
+	It("parses host-local ranges", func() {
+		ipam, err := ParseIPAMConfig([]byte(hostLocal))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ipam.Type).To(Equal(IPAMTypeHostLocal))
+		Expect(ipam.Ranges).To(HaveLen(2))
+		Expect(ipam.Ranges[0].Subnet.String()).To(Equal("10.1.0.0/24"))
+		Expect(ipam.Ranges[0].RangeStart.String()).To(Equal("10.1.0.10"))
+
+		ipam, err = ParseIPAMConfig([]byte(`{"type": "bridge", "ipam": {"type": "host-local", "subnet": "10.3.0.0/16"}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ipam.Ranges).To(HaveLen(1))
+		Expect(ipam.Ranges[0].Subnet.String()).To(Equal("10.3.0.0/16"))
+	})
+
+	It("parses whereabouts ranges", func() {
+		ipam, err := ParseIPAMConfig([]byte(whereabouts))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ipam.Type).To(Equal(IPAMTypeWhereabouts))
+		Expect(ipam.Ranges).To(HaveLen(2))
+		Expect(ipam.Ranges[0].Exclude).To(HaveLen(1))
+		Expect(ipam.Ranges[1].Subnet.String()).To(Equal("10.2.0.0/24"))
+		Expect(ipam.Ranges[1].RangeStart.String()).To(Equal("10.2.0.100"))
+		Expect(ipam.Ranges[1].RangeEnd.String()).To(Equal("10.2.0.110"))
+		Expect(ipam.Ranges[1].Contains(net.ParseIP("10.2.0.105"))).To(BeTrue())
+	})
+
+	It("returns nil without an IPAM section", func() {
+		ipam, err := ParseIPAMConfig([]byte(`{"type": "bridge"}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ipam).To(BeNil())
+	})
+
+	It("rejects malformed ranges", func() {
+		_, err := ParseIPAMConfig([]byte(`{"ipam": {"type": "whereabouts", "range": "10.0.0.0/33"}}`))
+		Expect(err).To(MatchError(ContainSubstring("invalid IPAM subnet")))
+	})
+
+	table.DescribeTable("validates requested IPs",
+		func(config string, ips []string, message string) {
+			err := ValidateIPRequest(newNetAttachDef(config), ips)
+			if message == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			}
+		},
+		table.Entry("host-local inside the range", hostLocal, []string{"10.1.0.15/24", "2001:db8::5"}, ""),
+		table.Entry("host-local outside the range", hostLocal, []string{"10.1.0.30"},
+			"requested IP 10.1.0.30 is outside the host-local range of network net-a"),
+		table.Entry("host-local outside the subnet", hostLocal, []string{"10.9.0.15"}, "outside"),
+		table.Entry("whereabouts inside the range", whereabouts, []string{"192.168.2.20", "10.2.0.100"}, ""),
+		table.Entry("whereabouts excluded", whereabouts, []string{"192.168.2.5"},
+			"requested IP 192.168.2.5 is excluded from the whereabouts range of network net-a"),
+		table.Entry("not an address", whereabouts, []string{"foo"}, `requested IP "foo" is not an IP address`),
+		table.Entry("unknown IPAM type", `{"ipam": {"type": "static"}}`, []string{"10.9.0.1"}, ""),
+		table.Entry("empty spec", "", []string{"10.9.0.1"}, ""),
+		table.Entry("whitespace-only spec", " \n\t", []string{"10.9.0.1"}, ""),
+	)
+})
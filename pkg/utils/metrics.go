@@ -0,0 +1,156 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+const metricsNamespace = "nad_client"
+
+// Reasons of the annotation parse failures counter
+const (
+	ParseFailureInvalidJSON   = "invalid_json"
+	ParseFailureInvalidFormat = "invalid_format"
+	// ParseFailureInvalidDefaultNetwork is a default network annotation
+	// that parses but does not name exactly one network, or names a
+	// network also requested as a secondary network
+	ParseFailureInvalidDefaultNetwork = "invalid_default_network"
+)
+
+var (
+	annotationParseFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "annotation_parse_failures_total",
+		Help:      "Number of pod network annotations that failed to parse, by annotation and reason.",
+	}, []string{"annotation", "reason"})
+
+	networkStatusUpdateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "network_status_update_duration_seconds",
+		Help:      "Latency of pod network status updates, including retries, by result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+
+	networkStatusUpdateRetries = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "network_status_update_retries",
+		Help:      "Number of conflict retries of pod network status updates.",
+		Buckets:   prometheus.LinearBuckets(0, 1, 6),
+	})
+)
+
+// RegisterMetrics registers the annotation parsing and network status update
+// metrics with registerer. The metrics are recorded whether or not they are
+// registered, so registering them is optional.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		annotationParseFailures,
+		networkStatusUpdateDuration,
+		networkStatusUpdateRetries,
+	} {
+		if err := registerer.Register(c); err != nil {
+			return fmt.Errorf("failed to register metrics: %v", err)
+		}
+	}
+	return nil
+}
+
+// parseFailureReason returns the reason of the parse failures counter for an
+// error of ParseNetworkAnnotation
+func parseFailureReason(err error) string {
+	var selectionErr *NetworkSelectionError
+	if errors.As(err, &selectionErr) {
+		return ParseFailureInvalidFormat
+	}
+	return ParseFailureInvalidJSON
+}
+
+// NetworkAttachmentIndex is the name of the pod index built by
+// NetworkAttachmentIndexFunc
+const NetworkAttachmentIndex = "network-attachment"
+
+// NetworkAttachmentIndexFunc indexes pods by the namespace/name of the
+// NetworkAttachmentDefinitions they request. Pods without or with an invalid
+// network selection annotation are not indexed. Pods are indexed on every
+// resync, so parse failures are not counted.
+func NetworkAttachmentIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("expected a pod, got %T", obj)
+	}
+	netAnnot := pod.Annotations[v1.NetworkAttachmentAnnot]
+	if netAnnot == "" {
+		return nil, nil
+	}
+	networks, err := ParseNetworkAnnotation(netAnnot, pod.Namespace)
+	if err != nil {
+		return nil, nil
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	for _, network := range networks {
+		key := network.Namespace + "/" + network.Name
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+var networkAttachmentPodsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(metricsNamespace, "", "network_attachment_pods"),
+	"Number of pods requesting a NetworkAttachmentDefinition.",
+	[]string{"namespace", "name"}, nil,
+)
+
+// networkUsageCollector exports the size of the NetworkAttachmentIndex of a
+// pod indexer as gauges
+type networkUsageCollector struct {
+	indexer cache.Indexer
+}
+
+// NewNetworkUsageCollector returns a collector exporting the number of pods
+// per NetworkAttachmentDefinition, read from indexer at scrape time. The
+// indexer, typically of a pod informer, must have the NetworkAttachmentIndex.
+func NewNetworkUsageCollector(indexer cache.Indexer) prometheus.Collector {
+	return &networkUsageCollector{indexer: indexer}
+}
+
+func (c *networkUsageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- networkAttachmentPodsDesc
+}
+
+func (c *networkUsageCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, key := range c.indexer.ListIndexFuncValues(NetworkAttachmentIndex) {
+		pods, err := c.indexer.ByIndex(NetworkAttachmentIndex, key)
+		if err != nil || len(pods) == 0 {
+			continue
+		}
+		parts := strings.SplitN(key, "/", 2)
+		ch <- prometheus.MustNewConstMetric(networkAttachmentPodsDesc, prometheus.GaugeValue,
+			float64(len(pods)), parts[0], parts[1])
+	}
+}
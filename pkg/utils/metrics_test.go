@@ -0,0 +1,145 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func sampleCount(observer prometheus.Observer) uint64 {
+	m := &dto.Metric{}
+	Expect(observer.(prometheus.Metric).Write(m)).To(Succeed())
+	return m.GetHistogram().GetSampleCount()
+}
+
+func newAnnotatedPod(namespace, name, networks string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        name,
+			Annotations: map[string]string{v1.NetworkAttachmentAnnot: networks},
+		},
+	}
+}
+
+var _ = Describe("Metrics", func() {
+	It("registers the collectors once", func() {
+		registry := prometheus.NewRegistry()
+		Expect(RegisterMetrics(registry)).To(Succeed())
+		Expect(RegisterMetrics(registry)).NotTo(Succeed())
+	})
+
+	It("counts pod annotation parse failures by annotation and reason", func() {
+		count := func(annotation, reason string) float64 {
+			return testutil.ToFloat64(annotationParseFailures.WithLabelValues(annotation, reason))
+		}
+		invalidJSON := count(v1.NetworkAttachmentAnnot, ParseFailureInvalidJSON)
+		invalidFormat := count(v1.NetworkAttachmentAnnot, ParseFailureInvalidFormat)
+		defaultFormat := count(v1.DefaultNetworkAnnot, ParseFailureInvalidFormat)
+		defaultNetwork := count(v1.DefaultNetworkAnnot, ParseFailureInvalidDefaultNetwork)
+
+		_, err := ParsePodNetworkAnnotation(newAnnotatedPod("ns1", "pod1", `[{"name":`))
+		Expect(err).To(HaveOccurred())
+		_, err = ParsePodNetworkAnnotation(newAnnotatedPod("ns1", "pod1", "a/b/c"))
+		Expect(err).To(HaveOccurred())
+		_, err = ParsePodNetworkAnnotation(newAnnotatedPod("ns1", "pod1", "net1"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = ParseNetworkAnnotation("a/b/c", "default")
+		Expect(err).To(HaveOccurred())
+
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "pod1",
+			Annotations: map[string]string{v1.DefaultNetworkAnnot: "a/b/c"}}}
+		_, err = ParsePodDefaultNetworkAnnotation(pod)
+		Expect(err).To(HaveOccurred())
+		pod.Annotations[v1.DefaultNetworkAnnot] = "net1,net2"
+		_, err = ParsePodDefaultNetworkAnnotation(pod)
+		Expect(err).To(HaveOccurred())
+
+		Expect(count(v1.NetworkAttachmentAnnot, ParseFailureInvalidJSON)).To(Equal(invalidJSON + 1))
+		Expect(count(v1.NetworkAttachmentAnnot, ParseFailureInvalidFormat)).To(Equal(invalidFormat + 1))
+		Expect(count(v1.DefaultNetworkAnnot, ParseFailureInvalidFormat)).To(Equal(defaultFormat + 1))
+		Expect(count(v1.DefaultNetworkAnnot, ParseFailureInvalidDefaultNetwork)).To(Equal(defaultNetwork + 1))
+	})
+
+	It("does not count parse failures when indexing pods", func() {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+			NetworkAttachmentIndex: NetworkAttachmentIndexFunc,
+		})
+		invalidFormat := testutil.ToFloat64(annotationParseFailures.WithLabelValues(v1.NetworkAttachmentAnnot, ParseFailureInvalidFormat))
+
+		pod := newAnnotatedPod("ns1", "pod1", "a/b/c")
+		Expect(indexer.Add(pod)).To(Succeed())
+		Expect(indexer.Update(pod)).To(Succeed())
+		Expect(indexer.Resync()).To(Succeed())
+
+		Expect(testutil.ToFloat64(annotationParseFailures.WithLabelValues(v1.NetworkAttachmentAnnot, ParseFailureInvalidFormat))).To(Equal(invalidFormat))
+	})
+
+	It("observes network status updates", func() {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod1"}}
+		client := fake.NewSimpleClientset(pod)
+
+		successes := sampleCount(networkStatusUpdateDuration.WithLabelValues("success"))
+		failures := sampleCount(networkStatusUpdateDuration.WithLabelValues("error"))
+		retries := sampleCount(networkStatusUpdateRetries)
+
+		Expect(SetNetworkStatus(client, pod, []v1.NetworkStatus{{Name: "net1", Interface: "net1"}})).To(Succeed())
+		missing := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod2"}}
+		Expect(SetNetworkStatus(client, missing, nil)).NotTo(Succeed())
+
+		Expect(sampleCount(networkStatusUpdateDuration.WithLabelValues("success"))).To(Equal(successes + 1))
+		Expect(sampleCount(networkStatusUpdateDuration.WithLabelValues("error"))).To(Equal(failures + 1))
+		Expect(sampleCount(networkStatusUpdateRetries)).To(Equal(retries + 2))
+	})
+
+	It("exports the pods per network attachment definition", func() {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+			NetworkAttachmentIndex: NetworkAttachmentIndexFunc,
+		})
+		Expect(indexer.Add(newAnnotatedPod("ns1", "pod1", "net1, net1@eth2, ns2/net2"))).To(Succeed())
+		Expect(indexer.Add(newAnnotatedPod("ns1", "pod2", "net1"))).To(Succeed())
+		Expect(indexer.Add(newAnnotatedPod("ns2", "pod3", "net2"))).To(Succeed())
+		Expect(indexer.Add(newAnnotatedPod("ns2", "pod4", "a/b/c"))).To(Succeed())
+		Expect(indexer.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns2", Name: "pod5"}})).To(Succeed())
+
+		expected := `
+# HELP nad_client_network_attachment_pods Number of pods requesting a NetworkAttachmentDefinition.
+# TYPE nad_client_network_attachment_pods gauge
+nad_client_network_attachment_pods{name="net1",namespace="ns1"} 2
+nad_client_network_attachment_pods{name="net2",namespace="ns2"} 2
+`
+		collector := NewNetworkUsageCollector(indexer)
+		Expect(testutil.CollectAndCompare(collector, strings.NewReader(expected))).To(Succeed())
+
+		Expect(indexer.Delete(newAnnotatedPod("ns1", "pod2", "net1"))).To(Succeed())
+		Expect(testutil.CollectAndCount(collector)).To(Equal(2))
+		Expect(indexer.Delete(newAnnotatedPod("ns1", "pod1", ""))).To(Succeed())
+		Expect(testutil.CollectAndCount(collector)).To(Equal(1))
+	})
+})
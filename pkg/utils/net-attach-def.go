@@ -19,8 +19,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
-	"regexp"
 	"strings"
+	"time"
 
 	cnitypes "github.com/containernetworking/cni/pkg/types"
 	cni100 "github.com/containernetworking/cni/pkg/types/100"
@@ -30,9 +30,20 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 )
 
+// Reasons of the Warning events recorded on pods
+const (
+	// NetworkStatusUpdateFailedReason is recorded when the network status of
+	// a pod cannot be written
+	NetworkStatusUpdateFailedReason = "NetworkStatusUpdateFailed"
+	// InvalidNetworkAnnotationReason is recorded when the network selection
+	// annotations of a pod cannot be parsed
+	InvalidNetworkAnnotationReason = "InvalidNetworkAnnotation"
+)
+
 // convertDNS converts CNI's DNS type to client DNS
 func convertDNS(dns cnitypes.DNS) *v1.DNS {
 	var v1dns v1.DNS
@@ -45,8 +56,51 @@ func convertDNS(dns cnitypes.DNS) *v1.DNS {
 	return &v1dns
 }
 
+// networkStatusOptions holds the settings of SetNetworkStatus
+type networkStatusOptions struct {
+	// legacy also writes LegacyNetworkStatusAnnot
+	legacy bool
+	// recorder, if set, records failed writes as pod events
+	recorder record.EventRecorder
+}
+
+// NetworkStatusOption configures how the network status is written
+type NetworkStatusOption func(*networkStatusOptions)
+
+// WithLegacyNetworkStatus also writes the network status to
+// LegacyNetworkStatusAnnot, for readers not migrated to NetworkStatusAnnot yet
+func WithLegacyNetworkStatus() NetworkStatusOption {
+	return func(o *networkStatusOptions) {
+		o.legacy = true
+	}
+}
+
+// WithEventRecorder records a NetworkStatusUpdateFailedReason Warning event
+// on the pod when writing its network status fails
+func WithEventRecorder(recorder record.EventRecorder) NetworkStatusOption {
+	return func(o *networkStatusOptions) {
+		o.recorder = recorder
+	}
+}
+
+func newNetworkStatusOptions(opts []NetworkStatusOption) networkStatusOptions {
+	var o networkStatusOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// recordFailure records err as an event on pod, if a recorder is set
+func (o networkStatusOptions) recordFailure(pod *corev1.Pod, err error) {
+	if o.recorder != nil {
+		o.recorder.Eventf(pod, corev1.EventTypeWarning, NetworkStatusUpdateFailedReason,
+			"Failed to update the network status: %v", err)
+	}
+}
+
 // SetNetworkStatus updates the Pod status
-func SetNetworkStatus(client kubernetes.Interface, pod *corev1.Pod, statuses []v1.NetworkStatus) error {
+func SetNetworkStatus(client kubernetes.Interface, pod *corev1.Pod, statuses []v1.NetworkStatus, opts ...NetworkStatusOption) error {
 	if client == nil {
 		return fmt.Errorf("no client set")
 	}
@@ -55,36 +109,62 @@ func SetNetworkStatus(client kubernetes.Interface, pod *corev1.Pod, statuses []v
 		return fmt.Errorf("no pod set")
 	}
 
-	var networkStatus []string
-	if statuses != nil {
-		for _, status := range statuses {
-			data, err := json.MarshalIndent(status, "", "    ")
-			if err != nil {
-				return fmt.Errorf("SetNetworkStatus: error with Marshal Indent: %v", err)
-			}
-			networkStatus = append(networkStatus, string(data))
-		}
+	o := newNetworkStatusOptions(opts)
+	networkStatus, err := encodeNetworkStatus(statuses)
+	if err != nil {
+		o.recordFailure(pod, err)
+		return fmt.Errorf("SetNetworkStatus: %v", err)
 	}
 
-	err := setPodNetworkStatus(client, pod, fmt.Sprintf("[%s]", strings.Join(networkStatus, ",")))
+	err = setPodNetworkStatus(client, pod, networkStatus, o)
 	if err != nil {
+		o.recordFailure(pod, err)
 		return fmt.Errorf("SetNetworkStatus: failed to update the pod %s in out of cluster comm: %v", pod.Name, err)
 	}
 	return nil
 }
 
-func setPodNetworkStatus(client kubernetes.Interface, pod *corev1.Pod, networkstatus string) error {
-	if len(pod.Annotations) == 0 {
-		pod.Annotations = make(map[string]string)
+// encodeNetworkStatus formats statuses the way they are stored in the
+// network status annotation
+func encodeNetworkStatus(statuses []v1.NetworkStatus) (string, error) {
+	var networkStatus []string
+	for _, status := range statuses {
+		data, err := json.MarshalIndent(status, "", "    ")
+		if err != nil {
+			return "", fmt.Errorf("error with Marshal Indent: %v", err)
+		}
+		networkStatus = append(networkStatus, string(data))
 	}
+	return fmt.Sprintf("[%s]", strings.Join(networkStatus, ",")), nil
+}
+
+func setPodNetworkStatus(client kubernetes.Interface, pod *corev1.Pod, networkstatus string, opts networkStatusOptions) error {
+	return updatePodAnnotations(client, pod, func(annotations map[string]string) (bool, error) {
+		writeNetworkStatus(annotations, networkstatus, opts)
+		return true, nil
+	})
+}
 
+func writeNetworkStatus(annotations map[string]string, networkstatus string, opts networkStatusOptions) {
+	annotations[v1.NetworkStatusAnnot] = networkstatus
+	if opts.legacy {
+		annotations[v1.LegacyNetworkStatusAnnot] = networkstatus
+	}
+}
+
+// updatePodAnnotations applies mutate to the annotations of the latest
+// version of pod and updates its status, retrying on conflicts. Nothing is
+// written if mutate returns false or an error.
+func updatePodAnnotations(client kubernetes.Interface, pod *corev1.Pod, mutate func(annotations map[string]string) (bool, error)) error {
 	coreClient := client.CoreV1()
-	var err error
 	name := pod.Name
 	namespace := pod.Namespace
 
+	start := time.Now()
+	attempts := 0
 	resultErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		pod, err = coreClient.Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		attempts++
+		pod, err := coreClient.Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
 		if err != nil {
 			return err
 		}
@@ -92,17 +172,205 @@ func setPodNetworkStatus(client kubernetes.Interface, pod *corev1.Pod, networkst
 		if len(pod.Annotations) == 0 {
 			pod.Annotations = make(map[string]string)
 		}
-		pod.Annotations[v1.NetworkStatusAnnot] = networkstatus
+		changed, err := mutate(pod.Annotations)
+		if err != nil || !changed {
+			return err
+		}
 		_, err = coreClient.Pods(namespace).UpdateStatus(context.TODO(), pod, metav1.UpdateOptions{})
 		return err
 	})
+
+	result := "success"
+	if resultErr != nil {
+		result = "error"
+	}
+	networkStatusUpdateDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	networkStatusUpdateRetries.Observe(float64(attempts - 1))
+
 	if resultErr != nil {
-		return fmt.Errorf("status update failed for pod %s/%s: %v", pod.Namespace, pod.Name, resultErr)
+		return fmt.Errorf("status update failed for pod %s/%s: %v", namespace, name, resultErr)
 	}
 	return nil
 }
 
-// GetNetworkStatus returns pod's network status
+// AddNetworkStatus appends status to the network status of pod. It fails if
+// pod already has a status for the same network name and interface.
+func AddNetworkStatus(client kubernetes.Interface, pod *corev1.Pod, status v1.NetworkStatus, opts ...NetworkStatusOption) error {
+	err := mergeNetworkStatus(client, pod, opts, func(statuses []v1.NetworkStatus) ([]v1.NetworkStatus, bool, error) {
+		if i := indexNetworkStatus(statuses, status.Name, status.Interface); i >= 0 {
+			return nil, false, fmt.Errorf("network status for %s interface %q already exists", status.Name, status.Interface)
+		}
+		return append(statuses, status), true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("AddNetworkStatus: %v", err)
+	}
+	return nil
+}
+
+// UpsertNetworkStatus replaces the status of pod for the network name and
+// interface of status, or appends status if there is none
+func UpsertNetworkStatus(client kubernetes.Interface, pod *corev1.Pod, status v1.NetworkStatus, opts ...NetworkStatusOption) error {
+	err := mergeNetworkStatus(client, pod, opts, func(statuses []v1.NetworkStatus) ([]v1.NetworkStatus, bool, error) {
+		if i := indexNetworkStatus(statuses, status.Name, status.Interface); i >= 0 {
+			statuses[i] = status
+			return statuses, true, nil
+		}
+		return append(statuses, status), true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("UpsertNetworkStatus: %v", err)
+	}
+	return nil
+}
+
+// RemoveNetworkStatus removes the status of pod for the network name and
+// interface. Removing a status that does not exist is not an error.
+func RemoveNetworkStatus(client kubernetes.Interface, pod *corev1.Pod, name, iface string, opts ...NetworkStatusOption) error {
+	err := mergeNetworkStatus(client, pod, opts, func(statuses []v1.NetworkStatus) ([]v1.NetworkStatus, bool, error) {
+		i := indexNetworkStatus(statuses, name, iface)
+		if i < 0 {
+			return nil, false, nil
+		}
+		return append(statuses[:i], statuses[i+1:]...), true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("RemoveNetworkStatus: %v", err)
+	}
+	return nil
+}
+
+// mergeNetworkStatus applies merge to the network status of the latest
+// version of pod, retrying on conflicts
+func mergeNetworkStatus(client kubernetes.Interface, pod *corev1.Pod, opts []NetworkStatusOption,
+	merge func(statuses []v1.NetworkStatus) ([]v1.NetworkStatus, bool, error)) error {
+	if client == nil {
+		return fmt.Errorf("no client set")
+	}
+	if pod == nil {
+		return fmt.Errorf("no pod set")
+	}
+
+	o := newNetworkStatusOptions(opts)
+	err := updatePodAnnotations(client, pod, func(annotations map[string]string) (bool, error) {
+		var statuses []v1.NetworkStatus
+		current, ok := annotations[v1.NetworkStatusAnnot]
+		if !ok {
+			current, ok = annotations[v1.LegacyNetworkStatusAnnot]
+		}
+		if ok {
+			if err := json.Unmarshal([]byte(current), &statuses); err != nil {
+				return false, fmt.Errorf("failed to parse the current network status: %v", err)
+			}
+		}
+		statuses, changed, err := merge(statuses)
+		if err != nil || !changed {
+			return false, err
+		}
+		networkStatus, err := encodeNetworkStatus(statuses)
+		if err != nil {
+			return false, err
+		}
+		writeNetworkStatus(annotations, networkStatus, o)
+		return true, nil
+	})
+	if err != nil {
+		o.recordFailure(pod, err)
+	}
+	return err
+}
+
+// indexNetworkStatus returns the index of the status for the network name
+// and interface, or -1
+func indexNetworkStatus(statuses []v1.NetworkStatus, name, iface string) int {
+	for i := range statuses {
+		if statuses[i].Name == name && statuses[i].Interface == iface {
+			return i
+		}
+	}
+	return -1
+}
+
+// MigrateNetworkStatus copies the network status of pod from
+// LegacyNetworkStatusAnnot to NetworkStatusAnnot and drops the legacy
+// annotation, unless WithLegacyNetworkStatus is given. On pods that already
+// carry NetworkStatusAnnot, the stale legacy annotation is dropped, or
+// replaced by the current status with WithLegacyNetworkStatus. Pods without
+// a legacy status are left alone. It returns whether the pod was rewritten.
+func MigrateNetworkStatus(client kubernetes.Interface, pod *corev1.Pod, opts ...NetworkStatusOption) (bool, error) {
+	if client == nil {
+		return false, fmt.Errorf("no client set")
+	}
+	if pod == nil {
+		return false, fmt.Errorf("no pod set")
+	}
+
+	o := newNetworkStatusOptions(opts)
+	migrated := false
+	err := updatePodAnnotations(client, pod, func(annotations map[string]string) (bool, error) {
+		migrated = false
+		legacy, ok := annotations[v1.LegacyNetworkStatusAnnot]
+		if !ok {
+			return false, nil
+		}
+		if current, ok := annotations[v1.NetworkStatusAnnot]; ok {
+			if o.legacy {
+				if legacy == current {
+					return false, nil
+				}
+				annotations[v1.LegacyNetworkStatusAnnot] = current
+			} else {
+				delete(annotations, v1.LegacyNetworkStatusAnnot)
+			}
+			migrated = true
+			return true, nil
+		}
+		annotations[v1.NetworkStatusAnnot] = legacy
+		if !o.legacy {
+			delete(annotations, v1.LegacyNetworkStatusAnnot)
+		}
+		migrated = true
+		return true, nil
+	})
+	if err != nil {
+		o.recordFailure(pod, err)
+		return false, fmt.Errorf("MigrateNetworkStatus: %v", err)
+	}
+	return migrated, nil
+}
+
+// MigrateNetworkStatuses runs MigrateNetworkStatus on every pod of namespace,
+// or of all namespaces if namespace is empty, and returns the number of pods
+// rewritten
+func MigrateNetworkStatuses(client kubernetes.Interface, namespace string, opts ...NetworkStatusOption) (int, error) {
+	if client == nil {
+		return 0, fmt.Errorf("no client set")
+	}
+
+	pods, err := client.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("MigrateNetworkStatuses: failed to list pods: %v", err)
+	}
+
+	count := 0
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if _, ok := pod.Annotations[v1.LegacyNetworkStatusAnnot]; !ok {
+			continue
+		}
+		migrated, err := MigrateNetworkStatus(client, pod, opts...)
+		if err != nil {
+			return count, err
+		}
+		if migrated {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetNetworkStatus returns pod's network status, read from NetworkStatusAnnot
+// or, if it is missing, from LegacyNetworkStatusAnnot
 func GetNetworkStatus(pod *corev1.Pod) ([]v1.NetworkStatus, error) {
 	if pod == nil {
 		return nil, fmt.Errorf("cannot find pod")
@@ -112,6 +380,10 @@ func GetNetworkStatus(pod *corev1.Pod) ([]v1.NetworkStatus, error) {
 	}
 
 	netStatusesJson, ok := pod.Annotations[v1.NetworkStatusAnnot]
+	if !ok {
+		// fall back to the annotation written by older Multus versions
+		netStatusesJson, ok = pod.Annotations[v1.LegacyNetworkStatusAnnot]
+	}
 	if !ok {
 		return nil, fmt.Errorf("cannot find network status")
 	}
@@ -168,8 +440,43 @@ func isDefaultRoute(route *cnitypes.Route) bool {
 		route.Dst.IP.Equal(net.IPv6zero)
 }
 
+// parseOptions holds the settings of the pod annotation parsers
+type parseOptions struct {
+	// recorder, if set, records parse failures as pod events
+	recorder record.EventRecorder
+}
+
+// ParseOption configures how the network annotations of a pod are parsed
+type ParseOption func(*parseOptions)
+
+// WithParseEventRecorder records an InvalidNetworkAnnotationReason Warning
+// event on the pod when its network annotations cannot be parsed
+func WithParseEventRecorder(recorder record.EventRecorder) ParseOption {
+	return func(o *parseOptions) {
+		o.recorder = recorder
+	}
+}
+
+func newParseOptions(opts []ParseOption) parseOptions {
+	var o parseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// recordFailure counts err as a parse failure of annotation for reason and
+// records it as an event on pod, if a recorder is set
+func (o parseOptions) recordFailure(pod *corev1.Pod, annotation, reason string, err error) {
+	annotationParseFailures.WithLabelValues(annotation, reason).Inc()
+	if o.recorder != nil {
+		o.recorder.Eventf(pod, corev1.EventTypeWarning, InvalidNetworkAnnotationReason,
+			"Invalid %s annotation: %v", annotation, err)
+	}
+}
+
 // ParsePodNetworkAnnotation parses Pod annotation for net-attach-def and get NetworkSelectionElement
-func ParsePodNetworkAnnotation(pod *corev1.Pod) ([]*v1.NetworkSelectionElement, error) {
+func ParsePodNetworkAnnotation(pod *corev1.Pod, opts ...ParseOption) ([]*v1.NetworkSelectionElement, error) {
 	netAnnot := pod.Annotations[v1.NetworkAttachmentAnnot]
 	defaultNamespace := pod.Namespace
 
@@ -179,11 +486,68 @@ func ParsePodNetworkAnnotation(pod *corev1.Pod) ([]*v1.NetworkSelectionElement,
 
 	networks, err := ParseNetworkAnnotation(netAnnot, defaultNamespace)
 	if err != nil {
+		newParseOptions(opts).recordFailure(pod, v1.NetworkAttachmentAnnot, parseFailureReason(err), err)
 		return nil, err
 	}
 	return networks, nil
 }
 
+// ParsePodDefaultNetworkAnnotation parses the Pod annotation overriding the
+// cluster default network. It returns nil if the annotation is not set, and
+// an error if it does not name exactly one network or if that network is
+// also requested as a secondary network.
+func ParsePodDefaultNetworkAnnotation(pod *corev1.Pod, opts ...ParseOption) (*v1.NetworkSelectionElement, error) {
+	if pod == nil {
+		return nil, fmt.Errorf("cannot find pod")
+	}
+
+	annot := pod.Annotations[v1.DefaultNetworkAnnot]
+	if len(annot) == 0 {
+		return nil, nil
+	}
+
+	o := newParseOptions(opts)
+	networks, err := ParseNetworkAnnotation(annot, pod.Namespace)
+	if err != nil {
+		o.recordFailure(pod, v1.DefaultNetworkAnnot, parseFailureReason(err), err)
+		return nil, fmt.Errorf("ParsePodDefaultNetworkAnnotation: %v", err)
+	}
+	if len(networks) != 1 {
+		err := fmt.Errorf("%s must name exactly one network, found %d", v1.DefaultNetworkAnnot, len(networks))
+		o.recordFailure(pod, v1.DefaultNetworkAnnot, ParseFailureInvalidDefaultNetwork, err)
+		return nil, fmt.Errorf("ParsePodDefaultNetworkAnnotation: %v", err)
+	}
+	defaultNetwork := networks[0]
+
+	secondaries, err := ParsePodNetworkAnnotation(pod, opts...)
+	if err != nil {
+		if _, ok := err.(*v1.NoK8sNetworkError); ok {
+			return defaultNetwork, nil
+		}
+		return nil, err
+	}
+	for _, network := range secondaries {
+		if network.Namespace == defaultNetwork.Namespace && network.Name == defaultNetwork.Name {
+			err := fmt.Errorf("default network %s/%s is also requested as a secondary network",
+				defaultNetwork.Namespace, defaultNetwork.Name)
+			o.recordFailure(pod, v1.DefaultNetworkAnnot, ParseFailureInvalidDefaultNetwork, err)
+			return nil, fmt.Errorf("ParsePodDefaultNetworkAnnotation: %v", err)
+		}
+	}
+	return defaultNetwork, nil
+}
+
+// IsPodDefaultNetwork returns true if pod overrides its default network with
+// the given network, so that its status can be created with defaultNetwork
+// set by CreateNetworkStatus
+func IsPodDefaultNetwork(pod *corev1.Pod, namespace, name string) (bool, error) {
+	defaultNetwork, err := ParsePodDefaultNetworkAnnotation(pod)
+	if err != nil || defaultNetwork == nil {
+		return false, err
+	}
+	return defaultNetwork.Namespace == namespace && defaultNetwork.Name == name, nil
+}
+
 // ParseNetworkAnnotation parses actual annotation string and get NetworkSelectionElement
 func ParseNetworkAnnotation(podNetworks, defaultNamespace string) ([]*v1.NetworkSelectionElement, error) {
 	var networks []*v1.NetworkSelectionElement
@@ -196,16 +560,18 @@ func ParseNetworkAnnotation(podNetworks, defaultNamespace string) ([]*v1.Network
 		if err := json.Unmarshal([]byte(podNetworks), &networks); err != nil {
 			return nil, fmt.Errorf("parsePodNetworkAnnotation: failed to parse pod Network Attachment Selection Annotation JSON format: %v", err)
 		}
+		for _, net := range networks {
+			if net == nil {
+				return nil, fmt.Errorf("parsePodNetworkAnnotation: null network selection element")
+			}
+		}
 	} else {
 		// Comma-delimited list of network attachment object names
-		for _, item := range strings.Split(podNetworks, ",") {
-			// Remove leading and trailing whitespace.
-			item = strings.TrimSpace(item)
-
+		for i, item := range strings.Split(podNetworks, ",") {
 			// Parse network name (i.e. <namespace>/<network name>@<ifname>)
-			netNsName, networkName, netIfName, err := parsePodNetworkObjectText(item)
+			netNsName, networkName, netIfName, err := parseNetworkSelectionItem(item, i)
 			if err != nil {
-				return nil, fmt.Errorf("parsePodNetworkAnnotation: %v", err)
+				return nil, fmt.Errorf("parsePodNetworkAnnotation: %w", err)
 			}
 
 			networks = append(networks, &v1.NetworkSelectionElement{
@@ -224,44 +590,3 @@ func ParseNetworkAnnotation(podNetworks, defaultNamespace string) ([]*v1.Network
 
 	return networks, nil
 }
-
-// parsePodNetworkObjectText parses annotation text and returns
-// its triplet, (namespace, name, interface name).
-func parsePodNetworkObjectText(podnetwork string) (string, string, string, error) {
-	var netNsName string
-	var netIfName string
-	var networkName string
-
-	slashItems := strings.Split(podnetwork, "/")
-	if len(slashItems) == 2 {
-		netNsName = strings.TrimSpace(slashItems[0])
-		networkName = slashItems[1]
-	} else if len(slashItems) == 1 {
-		networkName = slashItems[0]
-	} else {
-		return "", "", "", fmt.Errorf("Invalid network object (failed at '/')")
-	}
-
-	atItems := strings.Split(networkName, "@")
-	networkName = strings.TrimSpace(atItems[0])
-	if len(atItems) == 2 {
-		netIfName = strings.TrimSpace(atItems[1])
-	} else if len(atItems) != 1 {
-		return "", "", "", fmt.Errorf("Invalid network object (failed at '@')")
-	}
-
-	// Check and see if each item matches the specification for valid attachment name.
-	// "Valid attachment names must be comprised of units of the DNS-1123 label format"
-	// [a-z0-9]([-a-z0-9]*[a-z0-9])?
-	// And we allow at (@), and forward slash (/) (units separated by commas)
-	// It must start and end alphanumerically.
-	allItems := []string{netNsName, networkName, netIfName}
-	for i := range allItems {
-		matched, _ := regexp.MatchString("^[a-z0-9]([-a-z0-9]*[a-z0-9])?$", allItems[i])
-		if !matched && len([]rune(allItems[i])) > 0 {
-			return "", "", "", fmt.Errorf(fmt.Sprintf("Failed to parse: one or more items did not match comma-delimited format (must consist of lower case alphanumeric characters). Must start and end with an alphanumeric character), mismatch @ '%v'", allItems[i]))
-		}
-	}
-
-	return netNsName, networkName, netIfName, nil
-}
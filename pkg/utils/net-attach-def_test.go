@@ -16,6 +16,7 @@ package utils
 
 import (
 	"context"
+	"fmt"
 	"net"
 
 	cnitypes "github.com/containernetworking/cni/pkg/types"
@@ -26,6 +27,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -100,6 +102,172 @@ var _ = Describe("Netwok Attachment Definition manipulations", func() {
 		Expect(fakeStatus).To(Equal(getStatuses))
 	})
 
+	Context("legacy network status annotation", func() {
+		var clientSet *fake.Clientset
+		status := []v1.NetworkStatus{
+			{
+				Name:      "cbr0",
+				Interface: "eth0",
+				IPs:       []string{"10.244.1.2"},
+				Default:   true,
+			},
+		}
+		legacyStatus := `[{"name":"cbr0","interface":"eth0","ips":["10.244.1.2"],"default":true}]`
+
+		newPod := func(name string, annotations map[string]string) *corev1.Pod {
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   "fakeNamespace1",
+					Annotations: annotations,
+				},
+			}
+		}
+
+		getPod := func(name string) *corev1.Pod {
+			pod, err := clientSet.CoreV1().Pods("fakeNamespace1").Get(context.TODO(), name, metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			return pod
+		}
+
+		BeforeEach(func() {
+			clientSet = fake.NewSimpleClientset(
+				newPod("legacy", map[string]string{v1.LegacyNetworkStatusAnnot: legacyStatus}),
+				newPod("current", map[string]string{v1.NetworkStatusAnnot: legacyStatus}),
+				newPod("none", nil),
+			)
+		})
+
+		It("reads the legacy annotation if the current one is missing", func() {
+			statuses, err := GetNetworkStatus(getPod("legacy"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(statuses).To(Equal(status))
+
+			pod := newPod("both", map[string]string{
+				v1.NetworkStatusAnnot:       legacyStatus,
+				v1.LegacyNetworkStatusAnnot: "[]",
+			})
+			statuses, err = GetNetworkStatus(pod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(statuses).To(Equal(status))
+		})
+
+		It("writes both annotations on request", func() {
+			Expect(SetNetworkStatus(clientSet, getPod("none"), status, WithLegacyNetworkStatus())).To(Succeed())
+			pod := getPod("none")
+			Expect(pod.Annotations).To(HaveKey(v1.NetworkStatusAnnot))
+			Expect(pod.Annotations[v1.LegacyNetworkStatusAnnot]).To(Equal(pod.Annotations[v1.NetworkStatusAnnot]))
+
+			Expect(SetNetworkStatus(clientSet, getPod("current"), status)).To(Succeed())
+			Expect(getPod("current").Annotations).NotTo(HaveKey(v1.LegacyNetworkStatusAnnot))
+		})
+
+		It("migrates a pod", func() {
+			migrated, err := MigrateNetworkStatus(clientSet, getPod("legacy"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(migrated).To(BeTrue())
+			pod := getPod("legacy")
+			Expect(pod.Annotations).To(HaveKeyWithValue(v1.NetworkStatusAnnot, legacyStatus))
+			Expect(pod.Annotations).NotTo(HaveKey(v1.LegacyNetworkStatusAnnot))
+
+			migrated, err = MigrateNetworkStatus(clientSet, pod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(migrated).To(BeFalse())
+		})
+
+		It("drops a stale legacy annotation next to the current one", func() {
+			_, err := clientSet.CoreV1().Pods("fakeNamespace1").Create(context.TODO(), newPod("both", map[string]string{
+				v1.NetworkStatusAnnot:       legacyStatus,
+				v1.LegacyNetworkStatusAnnot: "[]",
+			}), metav1.CreateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			migrated, err := MigrateNetworkStatus(clientSet, getPod("both"), WithLegacyNetworkStatus())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(migrated).To(BeTrue())
+			Expect(getPod("both").Annotations).To(HaveKeyWithValue(v1.LegacyNetworkStatusAnnot, legacyStatus))
+
+			migrated, err = MigrateNetworkStatus(clientSet, getPod("both"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(migrated).To(BeTrue())
+			pod := getPod("both")
+			Expect(pod.Annotations).To(HaveKeyWithValue(v1.NetworkStatusAnnot, legacyStatus))
+			Expect(pod.Annotations).NotTo(HaveKey(v1.LegacyNetworkStatusAnnot))
+		})
+
+		It("migrates a namespace, keeping the legacy annotation on request", func() {
+			count, err := MigrateNetworkStatuses(clientSet, "fakeNamespace1", WithLegacyNetworkStatus())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(1))
+
+			pod := getPod("legacy")
+			Expect(pod.Annotations).To(HaveKeyWithValue(v1.NetworkStatusAnnot, legacyStatus))
+			Expect(pod.Annotations).To(HaveKeyWithValue(v1.LegacyNetworkStatusAnnot, legacyStatus))
+			Expect(getPod("none").Annotations).To(BeEmpty())
+		})
+	})
+
+	Context("incremental network status updates", func() {
+		var clientSet *fake.Clientset
+		eth0 := v1.NetworkStatus{Name: "cbr0", Interface: "eth0", IPs: []string{"10.244.1.2"}, Default: true}
+		net1 := v1.NetworkStatus{Name: "fakeNamespace1/net-a", Interface: "net1", IPs: []string{"1.1.1.1"}}
+		net2 := v1.NetworkStatus{Name: "fakeNamespace1/net-a", Interface: "net2", IPs: []string{"1.1.1.2"}}
+
+		getStatuses := func() []v1.NetworkStatus {
+			pod, err := clientSet.CoreV1().Pods("fakeNamespace1").Get(context.TODO(), "fakePod1", metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			statuses, err := GetNetworkStatus(pod)
+			Expect(err).NotTo(HaveOccurred())
+			return statuses
+		}
+
+		BeforeEach(func() {
+			clientSet = fake.NewSimpleClientset(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "fakePod1", Namespace: "fakeNamespace1"},
+			})
+		})
+
+		It("adds and removes single entries keyed by network and interface", func() {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "fakePod1", Namespace: "fakeNamespace1"}}
+			Expect(AddNetworkStatus(clientSet, pod, eth0)).To(Succeed())
+			Expect(AddNetworkStatus(clientSet, pod, net1)).To(Succeed())
+			Expect(AddNetworkStatus(clientSet, pod, net2)).To(Succeed())
+			Expect(getStatuses()).To(Equal([]v1.NetworkStatus{eth0, net1, net2}))
+
+			Expect(AddNetworkStatus(clientSet, pod, net1)).To(MatchError(ContainSubstring("already exists")))
+
+			Expect(RemoveNetworkStatus(clientSet, pod, net1.Name, "net1")).To(Succeed())
+			Expect(getStatuses()).To(Equal([]v1.NetworkStatus{eth0, net2}))
+			Expect(RemoveNetworkStatus(clientSet, pod, net1.Name, "net1")).To(Succeed())
+			Expect(getStatuses()).To(Equal([]v1.NetworkStatus{eth0, net2}))
+		})
+
+		It("upserts entries", func() {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "fakePod1", Namespace: "fakeNamespace1"}}
+			Expect(UpsertNetworkStatus(clientSet, pod, eth0)).To(Succeed())
+			Expect(UpsertNetworkStatus(clientSet, pod, net1)).To(Succeed())
+
+			updated := net1
+			updated.IPs = []string{"1.1.1.9"}
+			Expect(UpsertNetworkStatus(clientSet, pod, updated, WithLegacyNetworkStatus())).To(Succeed())
+			Expect(getStatuses()).To(Equal([]v1.NetworkStatus{eth0, updated}))
+
+			pod, err := clientSet.CoreV1().Pods("fakeNamespace1").Get(context.TODO(), "fakePod1", metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Annotations[v1.LegacyNetworkStatusAnnot]).To(Equal(pod.Annotations[v1.NetworkStatusAnnot]))
+		})
+
+		It("refuses to merge into a malformed status", func() {
+			pod, err := clientSet.CoreV1().Pods("fakeNamespace1").Get(context.TODO(), "fakePod1", metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			pod.Annotations = map[string]string{v1.NetworkStatusAnnot: "{"}
+			_, err = clientSet.CoreV1().Pods("fakeNamespace1").Update(context.TODO(), pod, metav1.UpdateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(AddNetworkStatus(clientSet, pod, net1)).To(MatchError(ContainSubstring("failed to parse the current network status")))
+		})
+	})
+
 	Context("create network status from cni result", func() {
 		var cniResult *cni100.Result
 		var networkStatus *v1.NetworkStatus
@@ -254,4 +422,122 @@ var _ = Describe("Netwok Attachment Definition manipulations", func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(elem).To(Equal(expectedElement))
 	})
+
+	Context("default network override", func() {
+		newPod := func(annotations map[string]string) *corev1.Pod {
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "fakePod1",
+					Namespace:   "fakeNamespace1",
+					Annotations: annotations,
+				},
+			}
+		}
+
+		It("parses the default network annotation", func() {
+			pod := newPod(map[string]string{
+				v1.DefaultNetworkAnnot:    "kube-system/calico",
+				v1.NetworkAttachmentAnnot: "net-a",
+			})
+			elem, err := ParsePodDefaultNetworkAnnotation(pod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(elem).To(Equal(&v1.NetworkSelectionElement{Name: "calico", Namespace: "kube-system"}))
+
+			isDefault, err := IsPodDefaultNetwork(pod, "kube-system", "calico")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isDefault).To(BeTrue())
+			isDefault, err = IsPodDefaultNetwork(pod, "fakeNamespace1", "net-a")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isDefault).To(BeFalse())
+
+			elem, err = ParsePodDefaultNetworkAnnotation(newPod(map[string]string{
+				v1.DefaultNetworkAnnot: `[{"name": "calico"}]`,
+			}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(elem).To(Equal(&v1.NetworkSelectionElement{Name: "calico", Namespace: "fakeNamespace1"}))
+		})
+
+		It("returns nil without the annotation", func() {
+			elem, err := ParsePodDefaultNetworkAnnotation(newPod(nil))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(elem).To(BeNil())
+
+			isDefault, err := IsPodDefaultNetwork(newPod(nil), "kube-system", "calico")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isDefault).To(BeFalse())
+		})
+
+		It("rejects more than one default network", func() {
+			_, err := ParsePodDefaultNetworkAnnotation(newPod(map[string]string{
+				v1.DefaultNetworkAnnot: "calico,flannel",
+			}))
+			Expect(err).To(MatchError(ContainSubstring("must name exactly one network, found 2")))
+		})
+
+		It("rejects a null default network", func() {
+			_, err := ParsePodDefaultNetworkAnnotation(newPod(map[string]string{
+				v1.DefaultNetworkAnnot: "[null]",
+			}))
+			Expect(err).To(MatchError(ContainSubstring("null network selection element")))
+		})
+
+		It("rejects a default network also requested as a secondary network", func() {
+			_, err := ParsePodDefaultNetworkAnnotation(newPod(map[string]string{
+				v1.DefaultNetworkAnnot:    "kube-system/calico",
+				v1.NetworkAttachmentAnnot: "net-a,kube-system/calico@net2",
+			}))
+			Expect(err).To(MatchError(ContainSubstring("default network kube-system/calico is also requested as a secondary network")))
+		})
+	})
+
+	Context("event recording", func() {
+		var recorder *record.FakeRecorder
+
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(10)
+		})
+
+		It("records failed network status updates", func() {
+			clientSet := fake.NewSimpleClientset()
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "fakePod1", Namespace: "fakeNamespace1"}}
+			status := v1.NetworkStatus{Name: "net-a", Interface: "net1"}
+
+			Expect(SetNetworkStatus(clientSet, pod, []v1.NetworkStatus{status}, WithEventRecorder(recorder))).NotTo(Succeed())
+			Expect(UpsertNetworkStatus(clientSet, pod, status, WithEventRecorder(recorder))).NotTo(Succeed())
+			Expect(recorder.Events).To(HaveLen(2))
+			Expect(<-recorder.Events).To(HavePrefix("Warning NetworkStatusUpdateFailed Failed to update the network status: "))
+
+			Expect(SetNetworkStatus(clientSet, pod, []v1.NetworkStatus{status})).NotTo(Succeed())
+			Expect(recorder.Events).To(HaveLen(1))
+		})
+
+		It("records nothing on success", func() {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "fakePod1", Namespace: "fakeNamespace1"}}
+			clientSet := fake.NewSimpleClientset(pod)
+			Expect(SetNetworkStatus(clientSet, pod, nil, WithEventRecorder(recorder))).To(Succeed())
+			Expect(recorder.Events).To(BeEmpty())
+		})
+
+		It("records invalid network annotations", func() {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:        "fakePod1",
+				Namespace:   "fakeNamespace1",
+				Annotations: map[string]string{v1.NetworkAttachmentAnnot: "a/b/c"},
+			}}
+			_, err := ParsePodNetworkAnnotation(pod, WithParseEventRecorder(recorder))
+			Expect(err).To(HaveOccurred())
+			Expect(recorder.Events).To(Receive(Equal(fmt.Sprintf("Warning InvalidNetworkAnnotation Invalid %s annotation: %v",
+				v1.NetworkAttachmentAnnot, err))))
+
+			pod.Annotations = map[string]string{v1.DefaultNetworkAnnot: "calico,flannel"}
+			_, err = ParsePodDefaultNetworkAnnotation(pod, WithParseEventRecorder(recorder))
+			Expect(err).To(HaveOccurred())
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning InvalidNetworkAnnotation Invalid " + v1.DefaultNetworkAnnot)))
+
+			pod.Annotations = nil
+			_, err = ParsePodNetworkAnnotation(pod, WithParseEventRecorder(recorder))
+			Expect(err).To(BeAssignableToTypeOf(&v1.NoK8sNetworkError{}))
+			Expect(recorder.Events).To(BeEmpty())
+		})
+	})
 })
@@ -0,0 +1,148 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// MaxInterfaceNameLength is the longest interface name the kernel accepts,
+// IFNAMSIZ minus the terminating NUL
+const MaxInterfaceNameLength = 15
+
+// Fields of a network selection item, see NetworkSelectionError
+const (
+	NetworkSelectionNamespace = "namespace"
+	NetworkSelectionName      = "name"
+	NetworkSelectionInterface = "interface"
+)
+
+// NetworkSelectionError is a syntax error in an item of a comma-delimited
+// network selection annotation, <namespace>/<network name>@<ifname>
+type NetworkSelectionError struct {
+	// Item is the offending item, without surrounding whitespace, and Index
+	// its position in the annotation, from 0
+	Item  string
+	Index int
+	// Field is the part of the item in error, empty for the item as a whole
+	Field string
+	// Offset is the byte offset in Item of the offending character, or -1
+	Offset int
+	Reason string
+}
+
+func (e *NetworkSelectionError) Error() string {
+	msg := fmt.Sprintf("invalid network selection item %d %q", e.Index, e.Item)
+	if e.Field != "" {
+		msg += ": " + e.Field
+	}
+	msg += ": " + e.Reason
+	if e.Offset >= 0 {
+		msg += fmt.Sprintf(" at offset %d", e.Offset)
+	}
+	return msg
+}
+
+// parseNetworkSelectionItem parses the item at index of a comma-delimited
+// network selection annotation. The namespace is empty if the item has none.
+// For compatibility with earlier parsers, an empty namespace or interface
+// before '/' or after '@' is accepted as if it was omitted; an empty name is
+// rejected.
+func parseNetworkSelectionItem(item string, index int) (namespace, name, iface string, err error) {
+	item = strings.TrimSpace(item)
+	fail := func(field string, offset int, format string, args ...interface{}) error {
+		return &NetworkSelectionError{
+			Item:   item,
+			Index:  index,
+			Field:  field,
+			Offset: offset,
+			Reason: fmt.Sprintf(format, args...),
+		}
+	}
+
+	if item == "" {
+		return "", "", "", fail("", -1, "empty item")
+	}
+
+	nameStart := 0
+	if i := strings.IndexByte(item, '/'); i >= 0 {
+		if j := strings.IndexByte(item[i+1:], '/'); j >= 0 {
+			return "", "", "", fail("", i+1+j, "unexpected '/'")
+		}
+		nameStart = i + 1
+	}
+	nameEnd, ifaceStart := len(item), -1
+	if i := strings.IndexByte(item[nameStart:], '@'); i >= 0 {
+		nameEnd = nameStart + i
+		if j := strings.IndexByte(item[nameEnd+1:], '@'); j >= 0 {
+			return "", "", "", fail("", nameEnd+1+j, "unexpected '@'")
+		}
+		ifaceStart = nameEnd + 1
+	}
+
+	if nameStart > 0 {
+		if namespace, err = parseLabel(item, 0, nameStart-1, validation.DNS1123LabelMaxLength, true, NetworkSelectionNamespace, fail); err != nil {
+			return "", "", "", err
+		}
+	}
+	if name, err = parseLabel(item, nameStart, nameEnd, validation.DNS1123SubdomainMaxLength, false, NetworkSelectionName, fail); err != nil {
+		return "", "", "", err
+	}
+	if ifaceStart >= 0 {
+		if iface, err = parseLabel(item, ifaceStart, len(item), MaxInterfaceNameLength, true, NetworkSelectionInterface, fail); err != nil {
+			return "", "", "", err
+		}
+	}
+	return namespace, name, iface, nil
+}
+
+// parseLabel returns item[start:end] without surrounding whitespace after
+// checking that it is a lower case DNS-1123 label of at most maxLength
+// characters, empty only if allowEmpty
+func parseLabel(item string, start, end, maxLength int, allowEmpty bool, field string,
+	fail func(field string, offset int, format string, args ...interface{}) error) (string, error) {
+	label := strings.TrimRightFunc(item[start:end], unicode.IsSpace)
+	trimmed := strings.TrimLeftFunc(label, unicode.IsSpace)
+	start += len(label) - len(trimmed)
+	label = trimmed
+
+	if label == "" {
+		if allowEmpty {
+			return "", nil
+		}
+		return "", fail(field, start, "must not be empty")
+	}
+	if len(label) > maxLength {
+		return "", fail(field, start+maxLength, "must be no more than %d characters", maxLength)
+	}
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' && i > 0 && i < len(label)-1:
+		case c == '-':
+			return "", fail(field, start+i, "must start and end with a lower case alphanumeric character")
+		default:
+			r, _ := utf8.DecodeRuneInString(label[i:])
+			return "", fail(field, start+i, "invalid character %q, must consist of lower case alphanumeric characters or '-'", r)
+		}
+	}
+	return label, nil
+}
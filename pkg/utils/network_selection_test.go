@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"strings"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Network selection annotation parsing", func() {
+	table.DescribeTable("parses valid comma-delimited annotations",
+		func(annotation string, expected []*v1.NetworkSelectionElement) {
+			networks, err := ParseNetworkAnnotation(annotation, "default")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(networks).To(Equal(expected))
+		},
+		table.Entry("a name", "net-a",
+			[]*v1.NetworkSelectionElement{{Namespace: "default", Name: "net-a"}}),
+		table.Entry("all the fields, with whitespace", " ns1 / net-a @ eth1 ,net-b@net2",
+			[]*v1.NetworkSelectionElement{
+				{Namespace: "ns1", Name: "net-a", InterfaceRequest: "eth1"},
+				{Namespace: "default", Name: "net-b", InterfaceRequest: "net2"},
+			}),
+		table.Entry("an empty namespace or interface, as earlier parsers", "/net-a, ns1/net-b@ ",
+			[]*v1.NetworkSelectionElement{
+				{Namespace: "default", Name: "net-a"},
+				{Namespace: "ns1", Name: "net-b"},
+			}),
+		table.Entry("the longest names", strings.Repeat("n", 63)+"/"+strings.Repeat("a", 253)+"@"+strings.Repeat("i", 15),
+			[]*v1.NetworkSelectionElement{{
+				Namespace:        strings.Repeat("n", 63),
+				Name:             strings.Repeat("a", 253),
+				InterfaceRequest: strings.Repeat("i", 15),
+			}}),
+	)
+
+	table.DescribeTable("reports where comma-delimited annotations are invalid",
+		func(annotation string, expected NetworkSelectionError, message string) {
+			_, err := ParseNetworkAnnotation(annotation, "default")
+			var selectionErr *NetworkSelectionError
+			Expect(errors.As(err, &selectionErr)).To(BeTrue())
+			Expect(*selectionErr).To(Equal(expected))
+			Expect(err).To(MatchError("parsePodNetworkAnnotation: " + message))
+		},
+		table.Entry("an upper case name", "net-a, ns1/Net-b",
+			NetworkSelectionError{Item: "ns1/Net-b", Index: 1, Field: NetworkSelectionName, Offset: 4,
+				Reason: "invalid character 'N', must consist of lower case alphanumeric characters or '-'"},
+			`invalid network selection item 1 "ns1/Net-b": name: invalid character 'N', must consist of lower case alphanumeric characters or '-' at offset 4`),
+		table.Entry("a name ending with a dash", "net-",
+			NetworkSelectionError{Item: "net-", Field: NetworkSelectionName, Offset: 3,
+				Reason: "must start and end with a lower case alphanumeric character"},
+			`invalid network selection item 0 "net-": name: must start and end with a lower case alphanumeric character at offset 3`),
+		table.Entry("too many slashes", "a/b/c",
+			NetworkSelectionError{Item: "a/b/c", Field: "", Offset: 3, Reason: "unexpected '/'"},
+			`invalid network selection item 0 "a/b/c": unexpected '/' at offset 3`),
+		table.Entry("too many ats", "net-a@eth0@eth1",
+			NetworkSelectionError{Item: "net-a@eth0@eth1", Offset: 10, Reason: "unexpected '@'"},
+			`invalid network selection item 0 "net-a@eth0@eth1": unexpected '@' at offset 10`),
+		table.Entry("an at in the namespace", "ns@1/net-a",
+			NetworkSelectionError{Item: "ns@1/net-a", Field: NetworkSelectionNamespace, Offset: 2,
+				Reason: "invalid character '@', must consist of lower case alphanumeric characters or '-'"},
+			`invalid network selection item 0 "ns@1/net-a": namespace: invalid character '@', must consist of lower case alphanumeric characters or '-' at offset 2`),
+		table.Entry("an empty item", "net-a,,net-b",
+			NetworkSelectionError{Item: "", Index: 1, Offset: -1, Reason: "empty item"},
+			`invalid network selection item 1 "": empty item`),
+		table.Entry("an empty name", "ns1/",
+			NetworkSelectionError{Item: "ns1/", Field: NetworkSelectionName, Offset: 4, Reason: "must not be empty"},
+			`invalid network selection item 0 "ns1/": name: must not be empty at offset 4`),
+		table.Entry("an empty name with an interface", "ns1/ @eth1",
+			NetworkSelectionError{Item: "ns1/ @eth1", Field: NetworkSelectionName, Offset: 4, Reason: "must not be empty"},
+			`invalid network selection item 0 "ns1/ @eth1": name: must not be empty at offset 4`),
+		table.Entry("a namespace too long", strings.Repeat("n", 64)+"/net-a",
+			NetworkSelectionError{Item: strings.Repeat("n", 64) + "/net-a", Field: NetworkSelectionNamespace, Offset: 63,
+				Reason: "must be no more than 63 characters"},
+			`invalid network selection item 0 "`+strings.Repeat("n", 64)+`/net-a": namespace: must be no more than 63 characters at offset 63`),
+		table.Entry("an interface name too long", "net-a@"+strings.Repeat("i", 16),
+			NetworkSelectionError{Item: "net-a@" + strings.Repeat("i", 16), Field: NetworkSelectionInterface, Offset: 21,
+				Reason: "must be no more than 15 characters"},
+			`invalid network selection item 0 "net-a@`+strings.Repeat("i", 16)+`": interface: must be no more than 15 characters at offset 21`),
+		table.Entry("a non-ASCII character", "nét",
+			NetworkSelectionError{Item: "nét", Field: NetworkSelectionName, Offset: 1,
+				Reason: "invalid character 'é', must consist of lower case alphanumeric characters or '-'"},
+			`invalid network selection item 0 "nét": name: invalid character 'é', must consist of lower case alphanumeric characters or '-' at offset 1`),
+	)
+
+	It("rejects a name longer than a DNS-1123 subdomain", func() {
+		_, err := ParseNetworkAnnotation(strings.Repeat("a", 254), "default")
+		Expect(err).To(MatchError(ContainSubstring("name: must be no more than 253 characters at offset 253")))
+	})
+
+	It("rejects null JSON elements", func() {
+		for _, annotation := range []string{`[null]`, `[{"name": "net-a"}, null]`} {
+			_, err := ParseNetworkAnnotation(annotation, "default")
+			Expect(err).To(MatchError("parsePodNetworkAnnotation: null network selection element"))
+		}
+		networks, err := ParseNetworkAnnotation(`[{}]`, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networks).To(Equal([]*v1.NetworkSelectionElement{{Namespace: "default"}}))
+	})
+})
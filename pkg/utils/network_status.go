@@ -0,0 +1,247 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+// NetworkStatusList is the network status of a pod, with lookup helpers.
+// The filters return lists so that they can be chained, e.g.
+// list.ByInterface("net1").IPv6().
+type NetworkStatusList []v1.NetworkStatus
+
+// GetNetworkStatusList returns the network status of pod as a
+// NetworkStatusList. The names of secondary networks are made canonical, see
+// CanonicalNetworkName.
+func GetNetworkStatusList(pod *corev1.Pod) (NetworkStatusList, error) {
+	statuses, err := GetNetworkStatus(pod)
+	if err != nil {
+		return nil, err
+	}
+	for i := range statuses {
+		if !statuses[i].Default {
+			statuses[i].Name = CanonicalNetworkName(statuses[i].Name, pod.Namespace)
+		}
+	}
+	return NetworkStatusList(statuses), nil
+}
+
+// filter returns the statuses for which match returns true
+func (l NetworkStatusList) filter(match func(status *v1.NetworkStatus) bool) NetworkStatusList {
+	var filtered NetworkStatusList
+	for i := range l {
+		if match(&l[i]) {
+			filtered = append(filtered, l[i])
+		}
+	}
+	return filtered
+}
+
+// ByInterface returns the statuses of the pod interface iface
+func (l NetworkStatusList) ByInterface(iface string) NetworkStatusList {
+	return l.filter(func(status *v1.NetworkStatus) bool {
+		return status.Interface == iface
+	})
+}
+
+// ByNetwork returns the statuses of the interfaces attached to the network
+// namespace/name. Statuses naming the network without a namespace only
+// match if namespace is empty.
+func (l NetworkStatusList) ByNetwork(namespace, name string) NetworkStatusList {
+	networkName := name
+	if namespace != "" {
+		networkName = namespace + "/" + name
+	}
+	return l.filter(func(status *v1.NetworkStatus) bool {
+		return status.Name == networkName
+	})
+}
+
+// Default returns the status of the cluster default network
+func (l NetworkStatusList) Default() NetworkStatusList {
+	return l.filter(func(status *v1.NetworkStatus) bool {
+		return status.Default
+	})
+}
+
+// IPv4 returns the IPv4 addresses of the statuses
+func (l NetworkStatusList) IPv4() []net.IP {
+	return l.ips(func(ip net.IP) bool { return ip.To4() != nil })
+}
+
+// IPv6 returns the IPv6 addresses of the statuses
+func (l NetworkStatusList) IPv6() []net.IP {
+	return l.ips(func(ip net.IP) bool { return ip.To4() == nil })
+}
+
+// ips returns the addresses of the statuses accepted by family. Addresses
+// may be in CIDR notation; invalid ones are skipped.
+func (l NetworkStatusList) ips(family func(ip net.IP) bool) []net.IP {
+	var ips []net.IP
+	for i := range l {
+		for _, s := range l[i].IPs {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				ip, _, _ = net.ParseCIDR(s)
+			}
+			if ip != nil && family(ip) {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips
+}
+
+// DeviceInfoFor returns the device info of the pod interface iface, or nil
+func (l NetworkStatusList) DeviceInfoFor(iface string) *v1.DeviceInfo {
+	for _, status := range l.ByInterface(iface) {
+		if status.DeviceInfo != nil {
+			return status.DeviceInfo
+		}
+	}
+	return nil
+}
+
+// CanonicalNetworkName returns the network name of a status in the
+// namespace/name form. Multus writes this form, other implementations may
+// write only the name of networks in the pod namespace.
+func CanonicalNetworkName(name, podNamespace string) string {
+	if strings.Contains(name, "/") {
+		return name
+	}
+	return podNamespace + "/" + name
+}
+
+// NetworkStatusMatches returns true if status is the attachment of the
+// secondary network requested by network. A request without an interface
+// matches any interface of the network; the default network never matches.
+func NetworkStatusMatches(network *v1.NetworkSelectionElement, status *v1.NetworkStatus, podNamespace string) bool {
+	if status.Default {
+		return false
+	}
+	if CanonicalNetworkName(status.Name, podNamespace) != network.Namespace+"/"+network.Name {
+		return false
+	}
+	return network.InterfaceRequest == "" || network.InterfaceRequest == status.Interface
+}
+
+// NetworkStatusMatch pairs a requested network with its status
+type NetworkStatusMatch struct {
+	Network *v1.NetworkSelectionElement
+	// Status is nil if the network is not attached
+	Status *v1.NetworkStatus
+}
+
+// MatchNetworkStatus correlates each of networks with an entry of statuses,
+// each entry being matched at most once. Requests naming an interface are
+// matched first, so that a request without one cannot claim their status.
+// It returns one match per network, in order, and the statuses left
+// unmatched.
+func MatchNetworkStatus(networks []*v1.NetworkSelectionElement, statuses []v1.NetworkStatus, podNamespace string) ([]NetworkStatusMatch, []v1.NetworkStatus) {
+	matches := make([]NetworkStatusMatch, len(networks))
+	matched := make([]bool, len(statuses))
+
+	for pass := 0; pass < 2; pass++ {
+		for i, network := range networks {
+			if (network.InterfaceRequest == "") != (pass == 1) {
+				continue
+			}
+			matches[i].Network = network
+			for j := range statuses {
+				if !matched[j] && NetworkStatusMatches(network, &statuses[j], podNamespace) {
+					matched[j] = true
+					matches[i].Status = &statuses[j]
+					break
+				}
+			}
+		}
+	}
+
+	var unmatched []v1.NetworkStatus
+	for j := range statuses {
+		if !matched[j] {
+			unmatched = append(unmatched, statuses[j])
+		}
+	}
+	return matches, unmatched
+}
+
+// MatchPodNetworkStatus correlates the networks requested by pod with its
+// network status. A default network override, see
+// ParsePodDefaultNetworkAnnotation, comes first and is matched with the
+// status of the default network.
+func MatchPodNetworkStatus(pod *corev1.Pod) ([]NetworkStatusMatch, error) {
+	if pod == nil {
+		return nil, fmt.Errorf("cannot find pod")
+	}
+
+	defaultNetwork, err := ParsePodDefaultNetworkAnnotation(pod)
+	if err != nil {
+		return nil, err
+	}
+	networks, err := ParsePodNetworkAnnotation(pod)
+	if err != nil {
+		if _, ok := err.(*v1.NoK8sNetworkError); !ok {
+			return nil, err
+		}
+	}
+
+	var statuses []v1.NetworkStatus
+	_, ok := pod.Annotations[v1.NetworkStatusAnnot]
+	if !ok {
+		_, ok = pod.Annotations[v1.LegacyNetworkStatusAnnot]
+	}
+	if ok {
+		if statuses, err = GetNetworkStatus(pod); err != nil {
+			return nil, fmt.Errorf("failed to parse the network status: %v", err)
+		}
+	}
+
+	matches, unmatched := MatchNetworkStatus(networks, statuses, pod.Namespace)
+	if defaultNetwork != nil {
+		match := NetworkStatusMatch{Network: defaultNetwork}
+		for i := range unmatched {
+			if unmatched[i].Default {
+				match.Status = &unmatched[i]
+				break
+			}
+		}
+		matches = append([]NetworkStatusMatch{match}, matches...)
+	}
+	return matches, nil
+}
+
+// MissingNetworkAttachments returns the networks requested by pod, including
+// a default network override, that have no network status
+func MissingNetworkAttachments(pod *corev1.Pod) ([]*v1.NetworkSelectionElement, error) {
+	matches, err := MatchPodNetworkStatus(pod)
+	if err != nil {
+		return nil, err
+	}
+	var missing []*v1.NetworkSelectionElement
+	for _, match := range matches {
+		if match.Status == nil {
+			missing = append(missing, match.Network)
+		}
+	}
+	return missing, nil
+}
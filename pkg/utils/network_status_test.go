@@ -0,0 +1,173 @@
+// Copyright (c) 2021 Kubernetes Network Plumbing Working Group
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"net"
+
+	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Network status list", func() {
+	devInfo := &v1.DeviceInfo{Type: v1.DeviceInfoTypePCI, Version: v1.DeviceInfoVersion, Pci: &v1.PciDevice{PciAddress: "0000:03:00.1"}}
+	list := NetworkStatusList{
+		{Name: "cbr0", Interface: "eth0", IPs: []string{"10.244.1.2", "fd00::2"}, Default: true},
+		{Name: "ns1/net-a", Interface: "net1", IPs: []string{"192.168.1.2/24", "2001:db8::2"}},
+		{Name: "ns1/net-a", Interface: "net2", IPs: []string{"192.168.1.3", "invalid"}, DeviceInfo: devInfo},
+		{Name: "net-b", Interface: "net3"},
+	}
+
+	It("filters by interface", func() {
+		Expect(list.ByInterface("net1")).To(Equal(list[1:2]))
+		Expect(list.ByInterface("net9")).To(BeEmpty())
+	})
+
+	It("filters by network", func() {
+		Expect(list.ByNetwork("ns1", "net-a")).To(Equal(list[1:3]))
+		Expect(list.ByNetwork("", "net-b")).To(Equal(list[3:]))
+		Expect(list.ByNetwork("ns1", "net-b")).To(BeEmpty())
+	})
+
+	It("returns the default network", func() {
+		Expect(list.Default()).To(Equal(list[:1]))
+		Expect(list[1:].Default()).To(BeEmpty())
+	})
+
+	It("returns addresses by family", func() {
+		Expect(list.Default().IPv4()).To(Equal([]net.IP{net.ParseIP("10.244.1.2")}))
+		Expect(list.ByInterface("net1").IPv6()).To(Equal([]net.IP{net.ParseIP("2001:db8::2")}))
+		Expect(list.ByNetwork("ns1", "net-a").IPv4()).To(Equal([]net.IP{net.ParseIP("192.168.1.2"), net.ParseIP("192.168.1.3")}))
+		Expect(list.ByInterface("net3").IPv4()).To(BeEmpty())
+	})
+
+	It("returns device info by interface", func() {
+		Expect(list.DeviceInfoFor("net2")).To(Equal(devInfo))
+		Expect(list.DeviceInfoFor("net1")).To(BeNil())
+		Expect(list.DeviceInfoFor("net9")).To(BeNil())
+	})
+
+	It("reads the list from a pod", func() {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod1",
+			Namespace: "ns1",
+			Annotations: map[string]string{
+				v1.NetworkStatusAnnot: `[{"name": "cbr0", "interface": "eth0", "ips": ["10.244.1.2"], "default": true}]`,
+			},
+		}}
+		statuses, err := GetNetworkStatusList(pod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses.Default().IPv4()).To(Equal([]net.IP{net.ParseIP("10.244.1.2")}))
+
+		_, err = GetNetworkStatusList(&corev1.Pod{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("makes the network names of a pod canonical", func() {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod1",
+			Namespace: "ns1",
+			Annotations: map[string]string{
+				v1.NetworkStatusAnnot: `[{"name": "cbr0", "interface": "eth0", "default": true},
+					{"name": "net-a", "interface": "net1"}, {"name": "ns2/net-b", "interface": "net2"}]`,
+			},
+		}}
+		statuses, err := GetNetworkStatusList(pod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses.Default()[0].Name).To(Equal("cbr0"))
+		Expect(statuses.ByNetwork("ns1", "net-a").ByInterface("net1")).To(HaveLen(1))
+		Expect(statuses.ByNetwork("ns2", "net-b").ByInterface("net2")).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("Network status matching", func() {
+	newPod := func(annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1", Annotations: annotations}}
+	}
+
+	It("matches both network name forms", func() {
+		network := &v1.NetworkSelectionElement{Namespace: "ns1", Name: "net-a"}
+		Expect(NetworkStatusMatches(network, &v1.NetworkStatus{Name: "net-a"}, "ns1")).To(BeTrue())
+		Expect(NetworkStatusMatches(network, &v1.NetworkStatus{Name: "ns1/net-a"}, "ns1")).To(BeTrue())
+		Expect(NetworkStatusMatches(network, &v1.NetworkStatus{Name: "net-a"}, "ns2")).To(BeFalse())
+		Expect(NetworkStatusMatches(network, &v1.NetworkStatus{Name: "ns1/net-a", Default: true}, "ns1")).To(BeFalse())
+
+		network.InterfaceRequest = "net2"
+		Expect(NetworkStatusMatches(network, &v1.NetworkStatus{Name: "net-a", Interface: "net1"}, "ns1")).To(BeFalse())
+		Expect(NetworkStatusMatches(network, &v1.NetworkStatus{Name: "net-a", Interface: "net2"}, "ns1")).To(BeTrue())
+	})
+
+	It("matches each status once, requests naming an interface first", func() {
+		networks := []*v1.NetworkSelectionElement{
+			{Namespace: "ns1", Name: "net-a"},
+			{Namespace: "ns1", Name: "net-a", InterfaceRequest: "net1"},
+			{Namespace: "ns1", Name: "net-b"},
+		}
+		statuses := []v1.NetworkStatus{
+			{Name: "cbr0", Interface: "eth0", Default: true},
+			{Name: "ns1/net-a", Interface: "net1"},
+			{Name: "net-a", Interface: "net2"},
+			{Name: "ns2/net-c", Interface: "net3"},
+		}
+		matches, unmatched := MatchNetworkStatus(networks, statuses, "ns1")
+		Expect(matches).To(Equal([]NetworkStatusMatch{
+			{Network: networks[0], Status: &statuses[2]},
+			{Network: networks[1], Status: &statuses[1]},
+			{Network: networks[2]},
+		}))
+		Expect(unmatched).To(Equal([]v1.NetworkStatus{statuses[0], statuses[3]}))
+	})
+
+	It("reports the requested attachments missing from the status", func() {
+		pod := newPod(map[string]string{
+			v1.DefaultNetworkAnnot:    "kube-system/calico",
+			v1.NetworkAttachmentAnnot: "net-a,ns2/net-b@net2",
+		})
+		missing, err := MissingNetworkAttachments(pod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(missing).To(HaveLen(3))
+
+		pod.Annotations[v1.NetworkStatusAnnot] = `[{"name": "k8s-pod-network", "interface": "eth0", "default": true},
+			{"name": "net-a", "interface": "net1"}]`
+		missing, err = MissingNetworkAttachments(pod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(missing).To(Equal([]*v1.NetworkSelectionElement{{Namespace: "ns2", Name: "net-b", InterfaceRequest: "net2"}}))
+
+		matches, err := MatchPodNetworkStatus(pod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(HaveLen(3))
+		Expect(matches[0].Network.Name).To(Equal("calico"))
+		Expect(matches[0].Status.Name).To(Equal("k8s-pod-network"))
+	})
+
+	It("treats pods without requests or status as having nothing missing", func() {
+		missing, err := MissingNetworkAttachments(newPod(nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(missing).To(BeEmpty())
+	})
+
+	It("fails on a malformed network status", func() {
+		_, err := MissingNetworkAttachments(newPod(map[string]string{
+			v1.NetworkAttachmentAnnot: "net-a",
+			v1.NetworkStatusAnnot:     "{",
+		}))
+		Expect(err).To(MatchError(ContainSubstring("failed to parse the network status")))
+	})
+})